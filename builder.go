@@ -0,0 +1,77 @@
+package service
+
+// Builder builds a Service from lifecycle hooks, without having to implement the Service interface by hand.
+// It is created using New and configured using its chainable methods. All hooks are optional.
+//
+// The built service keeps track of whether it is running or has stopped, and reports its health accordingly:
+// HealthStatusUnknown before it runs, HealthStatusHealthy while it runs, and HealthStatusShutdown or
+// HealthStatusError once it has stopped, depending on whether it failed. A health function set using
+// HealthFunc replaces the health reported while the service is running.
+type Builder struct {
+	name       string
+	namespace  string
+	version    string
+	initFn     func(*Context) error
+	runFn      func(*Context) error
+	shutdownFn func(*Context) error
+	healthFn   func() Health
+}
+
+// New returns a Builder for a service with the given name.
+func New(name string) *Builder {
+	return &Builder{
+		name: name,
+	}
+}
+
+// Namespace sets the namespace of the service.
+func (b *Builder) Namespace(namespace string) *Builder {
+	b.namespace = namespace
+	return b
+}
+
+// Version sets the version of the service.
+func (b *Builder) Version(version string) *Builder {
+	b.version = version
+	return b
+}
+
+// OnInit sets the function called when the service is initialized, see Service.Initialize.
+func (b *Builder) OnInit(fn func(*Context) error) *Builder {
+	b.initFn = fn
+	return b
+}
+
+// OnRun sets the function called when the service is run, see Service.Run.
+// The context passed to fn is canceled once the service is requested to shut down, so fn should return once it is done.
+// If no run function is set, the service runs until its context is done.
+func (b *Builder) OnRun(fn func(*Context) error) *Builder {
+	b.runFn = fn
+	return b
+}
+
+// OnShutdown sets the function called when the service is shut down, see Service.Shutdown.
+func (b *Builder) OnShutdown(fn func(*Context) error) *Builder {
+	b.shutdownFn = fn
+	return b
+}
+
+// HealthFunc sets the function reporting the health of the service while it is running.
+func (b *Builder) HealthFunc(fn func() Health) *Builder {
+	b.healthFn = fn
+	return b
+}
+
+// Build returns a new Service configured by the builder.
+// The builder can be reused afterwards; changes to it do not affect services that have already been built.
+func (b *Builder) Build() Service {
+	return &simpleService{
+		name:       b.name,
+		namespace:  b.namespace,
+		version:    b.version,
+		fn:         b.runFn,
+		initFn:     b.initFn,
+		shutdownFn: b.shutdownFn,
+		healthFn:   b.healthFn,
+	}
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+//go:generate go tool golang.org/x/tools/cmd/stringer -type CheckCriticality -trimprefix Check
+
+// CheckCriticality decides how a failing health check affects the health of a service, see Context.RegisterCheck.
+type CheckCriticality int
+
+const (
+	// CheckCritical indicates the service cannot work without the checked dependency:
+	// if the check fails, the service reports HealthStatusError.
+	CheckCritical CheckCriticality = iota
+	// CheckDegrading indicates the service can work with reduced functionality without the checked dependency:
+	// if the check fails, the service reports HealthStatusDegraded.
+	CheckDegrading
+)
+
+// CheckFunc checks the health of a single dependency of a service, e.g. a database, a cache or a downstream API.
+// It should return once the provided context is done, which is bounded by the timeout of the check.
+// Common checks can be created using TCPProbe, HTTPProbe, DNSProbe, ExecProbe and FileProbe.
+type CheckFunc = func(ctx context.Context) Health
+
+// CheckError returns a CheckFunc that reports HealthStatusHealthy if fn returns nil,
+// and HealthStatusError with the returned error otherwise.
+//
+// Example usage:
+//
+//	ctx.RegisterCheck("database", service.CheckError(db.PingContext))
+func CheckError(fn func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) Health {
+		if err := fn(ctx); err != nil {
+			return Health{
+				Status: HealthStatusError,
+				Reason: err.Error(),
+				Error:  err,
+			}
+		}
+
+		return Health{Status: HealthStatusHealthy}
+	}
+}
+
+// CheckOption is a function that modifies CheckOptions.
+// It is used to configure a health check registered using Context.RegisterCheck.
+type CheckOption = func(*CheckOptions)
+
+// CheckOptions holds options for a health check registered using Context.RegisterCheck.
+type CheckOptions struct {
+	// Timeout is the maximum duration of a single execution of the check.
+	// A check that does not return in time is reported as failing.
+	Timeout time.Duration
+	// Interval is the time between the start of two executions of the check.
+	Interval time.Duration
+	// Criticality decides how a failing check affects the health of the service.
+	Criticality CheckCriticality
+}
+
+// DefaultCheckOptions returns a CheckOptions struct with default values.
+// By default, checks are critical, run every 10 seconds and time out after 5 seconds.
+func DefaultCheckOptions() *CheckOptions {
+	return &CheckOptions{
+		Timeout:     5 * time.Second,
+		Interval:    10 * time.Second,
+		Criticality: CheckCritical,
+	}
+}
+
+// WithCheckTimeout returns a CheckOption that sets the maximum duration of a single execution of the check.
+// Non-positive timeouts are ignored.
+func WithCheckTimeout(timeout time.Duration) CheckOption {
+	return func(o *CheckOptions) {
+		if timeout > 0 {
+			o.Timeout = timeout
+		}
+	}
+}
+
+// WithCheckInterval returns a CheckOption that sets the time between two executions of the check.
+// Non-positive intervals are ignored.
+func WithCheckInterval(interval time.Duration) CheckOption {
+	return func(o *CheckOptions) {
+		if interval > 0 {
+			o.Interval = interval
+		}
+	}
+}
+
+// WithCheckCriticality returns a CheckOption that sets how a failing check affects the health of the service.
+func WithCheckCriticality(criticality CheckCriticality) CheckOption {
+	return func(o *CheckOptions) {
+		o.Criticality = criticality
+	}
+}
+
+// CheckResult is the cached result of the last execution of a health check, see Context.RegisterCheck.
+type CheckResult struct {
+	// Status is the status reported by the last execution of the check,
+	// or HealthStatusUnknown if the check has not completed yet.
+	Status HealthStatus
+	// Reason is the reason reported by the last execution of the check.
+	Reason string
+	// Details are the details reported by the last execution of the check.
+	Details any
+	// Criticality is the criticality of the check.
+	Criticality CheckCriticality
+	// Latency is the duration of the last execution of the check.
+	Latency time.Duration
+	// CheckedAt is the time the last execution of the check completed, or the zero time if it has not completed yet.
+	CheckedAt time.Time
+	// LastError is the error of the most recent failed execution of the check, which is retained
+	// once the check passes again, or nil if the check has never failed.
+	LastError error
+}
+
+// checkResultJSON is the JSON representation of a CheckResult.
+type checkResultJSON struct {
+	Status      HealthStatus     `json:"status"`
+	Reason      string           `json:"reason,omitempty"`
+	Details     any              `json:"details,omitempty"`
+	Criticality CheckCriticality `json:"criticality"`
+	Latency     string           `json:"latency"`
+	CheckedAt   *time.Time       `json:"checkedAt,omitempty"`
+	LastError   string           `json:"lastError,omitempty"`
+}
+
+// MarshalJSON encodes the CheckResult as a JSON object, where the latency is encoded as a duration string
+// and the last error as its message.
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	j := checkResultJSON{
+		Status:      r.Status,
+		Reason:      r.Reason,
+		Details:     r.Details,
+		Criticality: r.Criticality,
+		Latency:     r.Latency.String(),
+	}
+	if !r.CheckedAt.IsZero() {
+		j.CheckedAt = &r.CheckedAt
+	}
+	if r.LastError != nil {
+		j.LastError = r.LastError.Error()
+	}
+
+	return json.Marshal(j)
+}
+
+// MarshalText encodes the CheckCriticality as its name, e.g. "Critical".
+func (c CheckCriticality) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// failing reports whether the result counts as failing for the health of the service.
+func (r CheckResult) failing() bool {
+	return r.Status == HealthStatusError && r.Criticality == CheckCritical
+}
+
+// checkRegistry holds the health checks registered using Context.RegisterCheck during a single lifecycle of a service.
+type checkRegistry struct {
+	// ctx is the context of the lifecycle, which stops all checks once it is done.
+	ctx context.Context
+
+	mtx    sync.RWMutex
+	checks map[string]*check
+}
+
+// check is a single health check of a checkRegistry.
+type check struct {
+	fn     CheckFunc
+	opts   CheckOptions
+	cancel context.CancelFunc
+
+	mtx    sync.RWMutex
+	result CheckResult
+}
+
+// newCheckRegistry returns a checkRegistry whose checks run until the given context is done.
+func newCheckRegistry(ctx context.Context) *checkRegistry {
+	return &checkRegistry{
+		ctx:    ctx,
+		checks: map[string]*check{},
+	}
+}
+
+// RegisterCheck registers a health check with the given name, typically during Initialize.
+//
+// The check is executed in the background immediately and then in the configured interval, until the service
+// shuts down. Its result is cached, so that reporting the health of the service never blocks on a check.
+// The health of the service, as reported by Handle.Health, combines the health reported by the service with
+// the aggregated health of its checks, see Context.CheckHealth. A failing check makes the service report
+// HealthStatusError if it is critical, and HealthStatusDegraded otherwise. Checks affect the readiness of
+// the service (see Handle.Ready), but not its liveness (see Handle.Live).
+//
+// Registering a check with the name of an existing check replaces it.
+// Checks cannot be registered anymore once the service has shut down; such calls are logged and ignored.
+func (c *Context) RegisterCheck(name string, fn CheckFunc, opts ...CheckOption) {
+	o := DefaultCheckOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if c.checks == nil || !c.checks.add(c.logger.With("check", name), name, fn, *o) {
+		c.Warn("Health check not registered, service has already shut down", "check", name)
+	}
+}
+
+// CheckHealth returns the aggregated health of the checks registered using RegisterCheck, which is
+// HealthStatusError if any critical check fails, HealthStatusDegraded if any other check does not pass
+// or has not completed yet, and HealthStatusHealthy otherwise.
+// The result of each check is reported as a map from its name to its CheckResult in the details.
+func (c *Context) CheckHealth() Health {
+	if c.checks == nil {
+		return Health{Status: HealthStatusHealthy, Details: map[string]CheckResult{}}
+	}
+
+	return c.checks.health()
+}
+
+// add registers and starts a check, replacing an existing check with the same name.
+// It reports false if the context of the registry is already done.
+func (r *checkRegistry) add(logger *slog.Logger, name string, fn CheckFunc, opts CheckOptions) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.ctx.Err() != nil {
+		return false
+	}
+
+	if existing, ok := r.checks[name]; ok {
+		existing.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	chk := &check{
+		fn:     fn,
+		opts:   opts,
+		cancel: cancel,
+		result: CheckResult{
+			Status:      HealthStatusUnknown,
+			Criticality: opts.Criticality,
+		},
+	}
+	r.checks[name] = chk
+
+	go chk.run(ctx, logger)
+
+	return true
+}
+
+// len returns the number of registered checks.
+func (r *checkRegistry) len() int {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return len(r.checks)
+}
+
+// results returns the cached results of all checks by name.
+func (r *checkRegistry) results() map[string]CheckResult {
+	r.mtx.RLock()
+	checks := maps.Clone(r.checks)
+	r.mtx.RUnlock()
+
+	results := make(map[string]CheckResult, len(checks))
+	for name, chk := range checks {
+		results[name] = chk.getResult()
+	}
+
+	return results
+}
+
+// health returns the aggregated health of all checks.
+func (r *checkRegistry) health() Health {
+	results := r.results()
+
+	var failing, degraded int
+	var err error
+	for _, result := range results {
+		switch {
+		case result.failing():
+			failing++
+			if err == nil {
+				err = result.LastError
+			}
+		case result.Status != HealthStatusHealthy:
+			degraded++
+		}
+	}
+
+	switch {
+	case failing > 0:
+		return Health{
+			Status:  HealthStatusError,
+			Reason:  fmt.Sprintf("%d of %d checks failing", failing, len(results)),
+			Details: results,
+			Error:   err,
+		}
+	case degraded > 0:
+		return Health{
+			Status:  HealthStatusDegraded,
+			Reason:  fmt.Sprintf("%d of %d checks degraded", degraded, len(results)),
+			Details: results,
+		}
+	default:
+		return Health{Status: HealthStatusHealthy, Details: results}
+	}
+}
+
+// run executes the check in its interval until the given context is done.
+func (c *check) run(ctx context.Context, logger *slog.Logger) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.execute(ctx, logger)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute executes the check once, bounded by its timeout, and caches its result.
+// A check that does not return in time is left running in the background.
+func (c *check) execute(ctx context.Context, logger *slog.Logger) {
+	checkCtx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	started := time.Now()
+	sig := make(chan Health, 1)
+	go func() {
+		sig <- callCheck(checkCtx, c.fn)
+	}()
+
+	var health Health
+	select {
+	case health = <-sig:
+	case <-checkCtx.Done():
+		health = Health{
+			Status: HealthStatusError,
+			Reason: "check timed out",
+			Error:  fail.New().Msgf("check did not complete within %s", c.opts.Timeout),
+		}
+	}
+	latency := time.Since(started)
+
+	// the check is canceled if it has been replaced or the service has shut down
+	if ctx.Err() != nil {
+		return
+	}
+
+	if health.Status == HealthStatusError && health.Error == nil {
+		health.Error = fail.New().Msg(health.Reason)
+	}
+
+	c.mtx.Lock()
+	previous := c.result.Status
+	c.result = CheckResult{
+		Status:      health.Status,
+		Reason:      health.Reason,
+		Details:     health.Details,
+		Criticality: c.opts.Criticality,
+		Latency:     latency,
+		CheckedAt:   time.Now(),
+		LastError:   c.result.LastError,
+	}
+	if health.Error != nil {
+		c.result.LastError = health.Error
+	}
+	c.mtx.Unlock()
+
+	if health.Status != previous {
+		switch health.Status {
+		case HealthStatusHealthy:
+			logger.Info("Health check passed", "latency", latency.String())
+		default:
+			logger.Warn("Health check not passing", "status", health.Status.String(), "reason", health.Reason, "error", health.Error)
+		}
+	}
+}
+
+// getResult returns the cached result of the check.
+func (c *check) getResult() CheckResult {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.result
+}
+
+// callCheck calls fn, recovering from panics.
+func callCheck(ctx context.Context, fn CheckFunc) (health Health) {
+	defer func() {
+		if r := recover(); r != nil {
+			health = Health{
+				Status: HealthStatusError,
+				Reason: "check panicked",
+				Error:  fail.New().Attribute("panic", r).Msgf("check panicked: %v", r),
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// withCheckHealth combines the health reported by a running service with the aggregated health of its checks:
+// the status is the more severe of both (see healthSeverity), and the details contain the details of the
+// service under "service" and the results of the checks under "checks".
+// A service that reports it has been shut down is not affected by its checks.
+func withCheckHealth(health Health, checks Health) Health {
+	if health.Status == HealthStatusShutdown {
+		return health
+	}
+
+	details := map[string]any{"checks": checks.Details}
+	if health.Details != nil {
+		details["service"] = health.Details
+	}
+
+	if healthSeverity(checks.Status) > healthSeverity(health.Status) {
+		return Health{
+			Status:  checks.Status,
+			Reason:  checks.Reason,
+			Details: details,
+			Error:   checks.Error,
+		}
+	}
+
+	health.Details = details
+	return health
+}
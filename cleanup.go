@@ -0,0 +1,85 @@
+package service
+
+import (
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/FlowSeer/fail"
+)
+
+// cleanupRegistry holds the functions registered using Context.OnShutdown during a single lifecycle of a service.
+type cleanupRegistry struct {
+	mtx sync.Mutex
+	fns []func(*Context) error
+	// ran is set once the registered functions have been called.
+	ran bool
+}
+
+// OnShutdown registers fn to be called when the service is shut down, typically during Initialize.
+//
+// Registered functions are called by the runner after Service.Shutdown has returned, Run has returned and
+// all background tasks started using Go have returned, in reverse order of registration.
+// They are also called if Initialize fails, so resources acquired before the failure are released.
+// The Context passed to fn is bounded by the shutdown timeout of the service.
+// The errors of all functions are collected and reported as errors of the service.
+//
+// Functions registered after the service has shut down are called immediately.
+func (c *Context) OnShutdown(fn func(*Context) error) {
+	if c.cleanups == nil || !c.cleanups.add(fn) {
+		if err := callCleanup(c, fn); err != nil {
+			c.Error("Shutdown hook failed", "error", err)
+		}
+	}
+}
+
+// Closer registers the given io.Closer to be closed when the service is shut down, see OnShutdown.
+func (c *Context) Closer(closer io.Closer) {
+	c.OnShutdown(func(*Context) error {
+		return closer.Close()
+	})
+}
+
+// add registers a function. It reports false if the registered functions have already been called.
+func (r *cleanupRegistry) add(fn func(*Context) error) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.ran {
+		return false
+	}
+
+	r.fns = append(r.fns, fn)
+
+	return true
+}
+
+// run calls all registered functions in reverse order of registration, at most once.
+// A panicking function is recovered as a *PanicError, and the remaining functions are still called.
+func (r *cleanupRegistry) run(ctx *Context) error {
+	r.mtx.Lock()
+	fns := r.fns
+	r.fns = nil
+	r.ran = true
+	r.mtx.Unlock()
+
+	var errs []error
+	for _, fn := range slices.Backward(fns) {
+		if err := callCleanup(ctx, fn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return fail.WrapMany("Shutdown hooks encountered an error", errs...)
+}
+
+// callCleanup calls the given function registered using Context.OnShutdown, recovering a panic as a *PanicError.
+func callCleanup(ctx *Context, fn func(*Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(ctx.identity(), PhaseShuttingDown, r)
+		}
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// CompositeOption is a function that modifies CompositeOptions.
+// It is used to configure services created using CompositeWithOptions.
+type CompositeOption = func(*CompositeOptions)
+
+// CompositeOptions holds options for composite services.
+type CompositeOptions struct {
+	// Namespace is the namespace of the service.
+	Namespace string
+	// Version is the version of the service.
+	Version string
+}
+
+// DefaultCompositeOptions returns a CompositeOptions struct with default values.
+func DefaultCompositeOptions() *CompositeOptions {
+	return &CompositeOptions{}
+}
+
+// WithCompositeNamespace returns a CompositeOption that sets the namespace of the service.
+func WithCompositeNamespace(namespace string) CompositeOption {
+	return func(o *CompositeOptions) {
+		o.Namespace = namespace
+	}
+}
+
+// WithCompositeVersion returns a CompositeOption that sets the version of the service.
+func WithCompositeVersion(version string) CompositeOption {
+	return func(o *CompositeOptions) {
+		o.Version = version
+	}
+}
+
+// Composite returns a Service that runs the given child services as a group, so that a whole subsystem
+// can be run or composed like a single service.
+//
+// The children are started when the composite is run, and run like services passed to RunGroup:
+// if a child fails, all other children are shut down, and the composite returns an error wrapping the errors
+// of all failed children. Shutting down the composite shuts down all children, in reverse dependency order.
+// Children may depend on each other, see Dependent. Reloading the composite reloads all reloadable children.
+//
+// The Health of the composite aggregates the health of its children, which is reported as a map from the
+// identity of each child, as returned by Handle.String, to its Health in the details.
+// Signal handling is left to the runner of the composite.
+//
+// The namespace and version of the composite are empty, see CompositeWithOptions to set them.
+func Composite(name string, children ...Service) Service {
+	return CompositeWithOptions(name, children)
+}
+
+// CompositeWithOptions returns a Service that runs the given child services as a group like Composite,
+// configured using the given options.
+func CompositeWithOptions(name string, children []Service, opts ...CompositeOption) Service {
+	o := DefaultCompositeOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &compositeService{
+		name:      name,
+		namespace: o.Namespace,
+		version:   o.Version,
+		children:  children,
+	}
+}
+
+// compositeService is the Service returned by Composite.
+type compositeService struct {
+	name      string
+	namespace string
+	version   string
+	children  []Service
+
+	mtx sync.RWMutex
+	// run is the current run of the children, or nil if the composite has not been run yet.
+	run *compositeRun
+}
+
+// compositeRun is a single run of the children of a composite.
+type compositeRun struct {
+	runner   *Runner
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Name returns the unique name of the service.
+func (s *compositeService) Name() string {
+	return s.name
+}
+
+// Namespace returns the namespace of the service.
+func (s *compositeService) Namespace() string {
+	return s.namespace
+}
+
+// Version returns the version of the service implementation.
+func (s *compositeService) Version() string {
+	return s.version
+}
+
+// Health returns the aggregated health of the children.
+func (s *compositeService) Health() Health {
+	run := s.getRun()
+	if run == nil {
+		return Health{Status: HealthStatusUnknown}
+	}
+
+	healths := make(map[string]Health)
+	for _, h := range run.runner.Handles() {
+		healths[h.String()] = h.Health()
+	}
+
+	return aggregateHealth(healths)
+}
+
+// Initialize prepares the service for execution.
+// The children are initialized when the composite is run.
+func (s *compositeService) Initialize(_ *Context) error {
+	return nil
+}
+
+// Run runs the children as a group and blocks until all of them have finished.
+func (s *compositeService) Run(ctx *Context) error {
+	run := &compositeRun{runner: NewGroupRunner(childContext(ctx))}
+	s.setRun(run)
+
+	// the composite may have been shut down before its children were started
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err := run.runner.Add(s.children...); err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- run.runner.Wait()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// If the context was canceled for another reason than a shutdown request, e.g. because a service
+	// run together with the composite failed, Shutdown is not called before Run returns.
+	var shutdownErr *ShutdownRequestedError
+	if !errors.As(context.Cause(ctx), &shutdownErr) {
+		_ = run.stop(context.WithoutCancel(ctx))
+	}
+
+	return <-errCh
+}
+
+// Shutdown shuts down all children, using the provided context for cancellation and timeout.
+func (s *compositeService) Shutdown(ctx *Context) error {
+	run := s.getRun()
+	if run == nil {
+		return nil
+	}
+
+	return run.stop(ctx)
+}
+
+// Reload reloads all reloadable children.
+func (s *compositeService) Reload(ctx *Context) error {
+	run := s.getRun()
+	if run == nil {
+		return ErrServiceNotRunning
+	}
+
+	return run.runner.Reload(ctx)
+}
+
+func (s *compositeService) getRun() *compositeRun {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.run
+}
+
+func (s *compositeService) setRun(run *compositeRun) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.run = run
+}
+
+// stop shuts down all children at most once. It does nothing if all children have already finished.
+func (r *compositeRun) stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		if handles, ok := r.runner.markStopped(); ok {
+			r.stopErr = r.runner.shutdownHandles(ctx, handles)
+		}
+	})
+
+	return r.stopErr
+}
+
+// childContext returns the context for running the children of a service that runs other services, such as a composite.
+// The children are stopped by the parent rather than by the cancellation of the context, so that they are
+// shut down gracefully, in reverse dependency order and within the shutdown timeout.
+// Signal handling and the health server are disabled, as they are the responsibility of the runner of the parent.
+func childContext(ctx context.Context) context.Context {
+	ctx = context.WithoutCancel(ctx)
+	ctx = context.WithValue(ctx, shutdownSignalsKey{}, nil)
+	ctx = context.WithValue(ctx, reloadSignalsKey{}, nil)
+	ctx = context.WithValue(ctx, healthServerKey{}, (*HealthServerOptions)(nil))
+
+	return ctx
+}
+
+// aggregateHealth aggregates the health of multiple services into a single Health using the AllHealthy policy:
+// it is the most severe status of all services, where an unknown status counts as degraded,
+// and services that have been shut down are ignored unless all of them have been shut down.
+// The individual healths are reported as details.
+func aggregateHealth(healths map[string]Health) Health {
+	services := make([]ServiceHealth, 0, len(healths))
+	for name, health := range healths {
+		services = append(services, ServiceHealth{Service: name, Name: name, Health: health})
+	}
+
+	return aggregateServices(services, AllHealthy()).Health()
+}
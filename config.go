@@ -78,8 +78,14 @@ func ReadConfigFile[T any](ctx context.Context, path string, opts ...ConfigOptio
 
 // ReadConfigWithOptions reads configuration using the provided ConfigOptions struct.
 // Returns a pointer to the struct and an error, if any.
+// Errors are marked with ExitCategoryConfig, see ExitPolicy.
 func ReadConfigWithOptions[T any](ctx context.Context, opts *ConfigOptions) (*T, error) {
-	return readConfig[T](ctx, opts)
+	cfg, err := readConfig[T](ctx, opts)
+	if err != nil {
+		return nil, WithExitCategory(err, ExitCategoryConfig)
+	}
+
+	return cfg, nil
 }
 
 // WithConfigFilePath returns a ConfigOption that appends the given file path to the list of config files.
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"go.opentelemetry.io/otel/log"
@@ -29,6 +30,15 @@ type Context struct {
 	loggerShutdown OtelShutdownFunc
 
 	defaultMeter metric.Meter
+
+	// tasks tracks the background tasks started using Go during the current lifecycle of the service.
+	tasks *taskGroup
+	// cleanups holds the functions registered using OnShutdown during the current lifecycle of the service.
+	cleanups *cleanupRegistry
+	// checks holds the health checks registered using RegisterCheck during the current lifecycle of the service.
+	checks *checkRegistry
+	// attempt is the number of the current attempt of a Job, or zero outside of jobs.
+	attempt int
 }
 
 // LoggerProvider returns the OpenTelemetry LoggerProvider associated with this Context.
@@ -69,6 +79,12 @@ func (c *Context) Meter() metric.Meter {
 	return c.defaultMeter
 }
 
+// Attempt returns the number of the current attempt of a Job, starting at 1.
+// It returns 0 if the Context does not belong to a Job.
+func (c *Context) Attempt() int {
+	return c.attempt
+}
+
 // Debug logs an debug message using the Context's logger.
 func (c *Context) Debug(msg string, args ...any) {
 	c.logger.Debug(msg, args...)
@@ -88,3 +104,20 @@ func (c *Context) Warn(msg string, args ...any) {
 func (c *Context) Error(msg string, args ...any) {
 	c.logger.Error(msg, args...)
 }
+
+// withContext returns a shallow copy of the Context that uses the given context.Context
+// for cancellation, deadlines and values.
+func (c *Context) withContext(ctx context.Context) *Context {
+	cc := *c
+	cc.Context = ctx
+	return &cc
+}
+
+// identity returns the identity of the service the Context belongs to, in the format of Handle.String.
+func (c *Context) identity() string {
+	if namespace := Namespace(c); namespace != "" {
+		return fmt.Sprintf("%s/%s @ %s", namespace, Name(c), Version(c))
+	}
+
+	return fmt.Sprintf("%s @ %s", Name(c), Version(c))
+}
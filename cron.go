@@ -0,0 +1,233 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+// cronSearchLimit bounds the search for the next time matching a cron schedule,
+// so that schedules that never match, such as "0 0 30 2 *", do not loop forever.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronDescriptors maps the supported predefined schedules to their cron expressions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDays   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// CronSchedule is a parsed cron expression.
+// Use ParseCron to create a CronSchedule.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record whether the day-of-month and day-of-week fields start with a wildcard.
+	domAny, dowAny bool
+}
+
+// cronField describes one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	// names are the names accepted for the values of the field, starting at min.
+	names []string
+}
+
+// ParseCron parses a standard five-field cron expression: minute, hour, day of month, month and day of week.
+//
+// Each field may be a wildcard ("*"), a value ("5"), a range ("1-5"), a step ("*/15" or "0-30/10")
+// or a comma-separated list of those ("1,15,30"). Months and days of week may also be given by their
+// three-letter English names ("jan", "mon"), and Sunday may be given as both 0 and 7.
+// If both day of month and day of week are restricted, a time matches if either of them matches.
+// The predefined schedules @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly are supported as well.
+func ParseCron(spec string) (CronSchedule, error) {
+	expr := strings.TrimSpace(spec)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return CronSchedule{}, fail.New().
+			Attribute("spec", spec).
+			Msgf("invalid cron expression %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	defs := []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day of month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12, names: cronMonths},
+		{name: "day of week", min: 0, max: 7, names: cronDays},
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := defs[i].parse(field)
+		if err != nil {
+			return CronSchedule{}, fail.New().
+				Attribute("spec", spec).
+				Cause(err).
+				Msgf("invalid cron expression %q", spec)
+		}
+
+		bits[i] = b
+	}
+
+	// Sunday may be given as 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return CronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// Next returns the first time after t that matches the schedule, in the location of t.
+// Returns the zero time if no matching time is found within the next five years.
+func (c CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day-of-month and day-of-week fields.
+func (c CronSchedule) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// parse parses a single field of a cron expression into a bit set of the matching values.
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fail.Msgf("invalid step %q in %s field", stepStr, f.name)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rng, "-"):
+			loStr, hiStr, _ := strings.Cut(rng, "-")
+
+			var err error
+			if lo, err = f.value(loStr); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiStr); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fail.Msgf("invalid range %q in %s field", rng, f.name)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+
+			// a single value with a step, e.g. "5/15", is a range up to the maximum
+			hi = lo
+			if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// value parses a single value of the field, which may be a number or a name.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fail.Msgf("invalid value %q in %s field, expected %d-%d", s, f.name, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// Cron returns a Service that calls fn whenever the current time matches the given cron expression
+// while it is running. See ParseCron for the supported syntax. The schedule is interpreted in the location
+// configured using WithLocation, which defaults to the local time zone.
+//
+// Failing runs, cancellation and health reporting behave as described for Periodic.
+// An invalid cron expression makes the service fail to initialize.
+func Cron(name string, spec string, fn func(*Context) error, opts ...ScheduleOption) Service {
+	o := DefaultScheduleOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	schedule, err := ParseCron(spec)
+
+	return newScheduledService(name, o, fn, func(t time.Time) time.Time {
+		next := schedule.Next(t.In(o.Location))
+		if next.IsZero() {
+			// the schedule never matches, so never run again
+			return t.Add(cronSearchLimit)
+		}
+
+		return next
+	}, err)
+}
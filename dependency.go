@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+// dependencyPollInterval is the interval in which the readiness of dependencies is checked.
+const dependencyPollInterval = 100 * time.Millisecond
+
+// Dependency identifies a service another service depends on.
+type Dependency struct {
+	// Name is the name of the service depended on.
+	Name string
+	// Namespace is the namespace of the service depended on.
+	// If empty, the namespace of the depending service is used.
+	Namespace string
+}
+
+// Dependent can optionally be implemented by a Service to declare the services it depends on.
+//
+// A Runner does not initialize a dependent service before all of its dependencies are in PhaseRunning
+// and report HealthStatusHealthy. When the runner is stopped, dependent services are shut down before their dependencies.
+// All dependencies must be run by the same runner, either in the same call or before the dependent service.
+// If a dependency cannot be found or the dependencies form a cycle, the runner refuses to start the services.
+type Dependent interface {
+	// Dependencies returns the services this service depends on.
+	Dependencies() []Dependency
+}
+
+// DependsOn returns a Dependency on the service with the given name in the same namespace as the depending service.
+func DependsOn(name string) Dependency {
+	return Dependency{Name: name}
+}
+
+// String returns the identity of the service depended on.
+func (d Dependency) String() string {
+	if d.Namespace != "" {
+		return d.Namespace + "/" + d.Name
+	}
+
+	return d.Name
+}
+
+// DependencyCycleError is returned when the dependencies of services form a cycle.
+type DependencyCycleError struct {
+	// Cycle contains the identities of the services forming the cycle.
+	// The first service is repeated at the end to close the cycle.
+	Cycle []string
+}
+
+// Error returns a human-readable description of the dependency cycle.
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// dependenciesOf returns the dependencies declared by the given service, with namespaces resolved.
+func dependenciesOf(svc Service) []Dependency {
+	d, ok := svc.(Dependent)
+	if !ok {
+		return nil
+	}
+
+	deps := d.Dependencies()
+	for i := range deps {
+		if deps[i].Namespace == "" {
+			deps[i].Namespace = svc.Namespace()
+		}
+	}
+
+	return deps
+}
+
+// matches reports whether the dependency refers to a service with the given name and namespace.
+func (d Dependency) matches(name, namespace string) bool {
+	return d.Name == name && d.Namespace == namespace
+}
+
+// resolveDependencies resolves the dependencies of the given handles, which are about to be started,
+// among all handles including already started ones. It returns an error if a dependency cannot be found
+// or if the dependencies form a cycle.
+func resolveDependencies(started []*Handle, handles []*Handle) error {
+	all := append(slices.Clone(started), handles...)
+
+	for _, h := range handles {
+		for _, dep := range dependenciesOf(h.svc) {
+			n := len(h.dependencies)
+			for _, other := range all {
+				if dep.matches(other.name, other.namespace) {
+					h.dependencies = append(h.dependencies, other)
+				}
+			}
+
+			if len(h.dependencies) == n {
+				return fail.New().
+					Attribute("service", h.String()).
+					Attribute("dependency", dep.String()).
+					Msgf("service %s depends on unknown service %s", h, dep)
+			}
+		}
+	}
+
+	// Already started handles cannot depend on the new ones,
+	// so any cycle must pass through one of the new handles.
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[*Handle]int)
+	var path []*Handle
+
+	var visit func(h *Handle) error
+	visit = func(h *Handle) error {
+		switch state[h] {
+		case visited:
+			return nil
+		case visiting:
+			i := slices.Index(path, h)
+			cycle := make([]string, 0, len(path)-i+1)
+			for _, p := range path[i:] {
+				cycle = append(cycle, p.String())
+			}
+
+			return &DependencyCycleError{Cycle: append(cycle, h.String())}
+		}
+
+		state[h] = visiting
+		path = append(path, h)
+		for _, dep := range h.dependencies {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[h] = visited
+
+		return nil
+	}
+
+	for _, h := range handles {
+		if err := visit(h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForDependencies blocks until all dependencies of the service managed by the handle
+// are running and healthy. It returns early without an error if the service is requested to stop,
+// and with an error if a dependency exits before becoming ready or the service context is done.
+func (h *Handle) waitForDependencies() error {
+	if len(h.dependencies) == 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	for _, dep := range h.dependencies {
+		if dep.isReady() {
+			continue
+		}
+
+		h.svcContext.Debug("Waiting for dependency", "dependency", dep.String())
+		for !dep.isReady() {
+			select {
+			case <-ticker.C:
+			case <-dep.exitSig:
+				return fail.New().
+					Attribute("service", h.String()).
+					Attribute("dependency", dep.String()).
+					Cause(dep.Error()).
+					Msgf("dependency %s exited before becoming ready", dep)
+			case <-h.stopSig:
+				return nil
+			case <-h.svcContext.Done():
+				return context.Cause(h.svcContext)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isReady reports whether the service managed by the handle is running and healthy.
+// A service that is reloading is still running.
+func (h *Handle) isReady() bool {
+	phase := h.Phase()
+	return (phase == PhaseRunning || phase == PhaseReloading) && h.svc.Health().Status == HealthStatusHealthy
+}
+
+// dependsOn reports whether the service managed by the handle directly depends on the other one.
+func (h *Handle) dependsOn(other *Handle) bool {
+	return slices.Contains(h.dependencies, other)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// DrainDelayEnvVar is the environment variable used to configure the drain delay of a service.
+// The value must be a duration parsable by time.ParseDuration, e.g. "5s".
+const DrainDelayEnvVar = "DRAIN_DELAY"
+
+// drainDelayKey is the context key type for storing the drain delay.
+type drainDelayKey struct{}
+
+// Drainer can optionally be implemented by a Service to stop accepting new work before it is shut down.
+//
+// When a running service is requested to shut down, it first enters PhaseDraining: Drain is called,
+// and the drain delay of the service elapses before its context is canceled and Service.Shutdown is called.
+// While draining, the service reports not ready (see Handle.Ready) but stays live (see Handle.Live),
+// so load balancers can stop routing traffic to it while in-flight requests are still being served.
+type Drainer interface {
+	// Drain stops the service from accepting new work, e.g. by failing its readiness checks.
+	// The provided context is bounded by the shutdown timeout of the service.
+	Drain(*Context) error
+}
+
+// WithDrainDelay returns a new context with the specified drain delay.
+// The drain delay is the time a service stays in PhaseDraining before it is shut down,
+// e.g. to give load balancers time to deregister it. It counts towards the shutdown timeout.
+func WithDrainDelay(ctx context.Context, delay time.Duration) context.Context {
+	return context.WithValue(ctx, drainDelayKey{}, delay)
+}
+
+// DrainDelay retrieves the drain delay from the context, if present.
+// The boolean reports whether a drain delay has been set.
+func DrainDelay(ctx context.Context) (time.Duration, bool) {
+	delay, ok := ctx.Value(drainDelayKey{}).(time.Duration)
+	return delay, ok
+}
+
+// DrainDelayFromEnv reads the drain delay from environment variables.
+// If prefix is provided, it will look for {PREFIX}_DRAIN_DELAY.
+// If prefix is empty, it will look for SERVICE_DRAIN_DELAY.
+// Returns zero, i.e. no delay, if the variable is unset or not a valid, positive duration.
+func DrainDelayFromEnv(prefix string) time.Duration {
+	return durationFromEnv(prefix, DrainDelayEnvVar, 0)
+}
+
+// drainDelay determines the drain delay of the given service,
+// taken from the context if set, or from the environment otherwise.
+func drainDelay(ctx context.Context, svc Service) time.Duration {
+	if delay, ok := DrainDelay(ctx); ok {
+		return max(delay, 0)
+	}
+
+	if delay := DrainDelayFromEnv(envPrefix(ctx, svc)); delay > 0 {
+		return delay
+	}
+
+	return DrainDelayFromEnv("")
+}
+
+// drain drains the given lifecycle of the service if it is running: the service transitions to PhaseDraining,
+// Drain is called if the service implements Drainer, and the drain delay elapses.
+// It returns early once the shutdown context is done.
+func (h *Handle) drain(shutdownCtx context.Context, lc *lifecycle) error {
+	if lc.isRunDone() {
+		return nil
+	}
+
+	draining := h.transitionPhase(PhaseDraining, nil, func(current Phase) bool {
+		return current == PhaseRunning || current == PhaseReloading
+	})
+	if !draining {
+		return nil
+	}
+
+	d, ok := h.svc.(Drainer)
+	if !ok && h.drainDelay <= 0 {
+		return nil
+	}
+
+	lc.ctx.Info("Draining", "delay", h.drainDelay.String())
+
+	var err error
+	if ok {
+		drainFn := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = newPanicError(h.String(), PhaseDraining, r)
+				}
+			}()
+
+			return d.Drain(lc.ctx.withContext(shutdownCtx))
+		}
+
+		if err = drainFn(); err != nil {
+			lc.ctx.Warn("Draining failed", "error", err)
+		}
+	}
+
+	if h.drainDelay > 0 {
+		timer := time.NewTimer(h.drainDelay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-lc.runDone:
+		case <-shutdownCtx.Done():
+		}
+	}
+
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/FlowSeer/fail"
 )
@@ -110,6 +111,23 @@ func GetEnvOrDefault(prefix string, name string, defaultValue string) string {
 	return defaultValue
 }
 
+// durationFromEnv reads a duration from the environment variable constructed from the given prefix and name.
+// The value must be parsable by time.ParseDuration.
+// Returns defaultValue if the variable is unset or not a valid, positive duration.
+func durationFromEnv(prefix string, name string, defaultValue time.Duration) time.Duration {
+	value, ok := LookupEnv(prefix, name)
+	if !ok {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d <= 0 {
+		return defaultValue
+	}
+
+	return d
+}
+
 // MustGetEnv retrieves the value of the environment variable constructed from the given prefix and name.
 // The environment variable name is normalized using EnvName(prefix, name).
 // If the variable is not set, MustGetEnv returns an error indicating that the environment variable must be set.
@@ -1,12 +1,28 @@
 package service
 
-import "github.com/FlowSeer/fail"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
 
 var (
 	// ErrServiceAlreadyRunning indicates that the service is already running.
 	ErrServiceAlreadyRunning = fail.Msg("service is already running")
 	// ErrServiceAlreadyStopped indicates that the service has already been run and stopped.
 	ErrServiceAlreadyStopped = fail.Msg("service has already been run and is stopped")
+	// ErrServiceNotRunning indicates that the service is not running.
+	ErrServiceNotRunning = fail.Msg("service is not running")
+	// ErrServiceNotReloadable indicates that the service does not implement Reloadable.
+	ErrServiceNotReloadable = fail.Msg("service does not support reloading")
+	// ErrServiceNotPausable indicates that the service does not implement Pausable.
+	ErrServiceNotPausable = fail.Msg("service does not support pausing")
+	// ErrServiceNotPaused indicates that the service is not paused.
+	ErrServiceNotPaused = fail.Msg("service is not paused")
 	// ErrRunnerWaiting indicates that the runner is currently waiting for services to complete their execution.
 	ErrRunnerWaiting = fail.Msg("runner is waiting for services to finish")
 	// ErrRunnerAlreadyWaiting indicates that the runner is already waiting for services to complete their execution.
@@ -14,3 +30,241 @@ var (
 	// ErrRunnerStopped indicates that the runner has been stopped.
 	ErrRunnerStopped = fail.Msg("runner is stopped")
 )
+
+// ShutdownTimeoutError is returned by Handle.Shutdown and Handle.Wait when a service does not shut down
+// within its shutdown timeout, or when the context passed to Handle.Shutdown is done before the service has shut down.
+type ShutdownTimeoutError struct {
+	// Service is the identity of the service that did not shut down in time, as returned by Handle.String.
+	Service string
+	// Timeout is the shutdown timeout of the service.
+	Timeout time.Duration
+	// Cause is the reason the shutdown was aborted, usually context.DeadlineExceeded or context.Canceled.
+	Cause error
+}
+
+// Error returns a human-readable description of the shutdown timeout.
+func (e *ShutdownTimeoutError) Error() string {
+	if errors.Is(e.Cause, context.DeadlineExceeded) {
+		return fmt.Sprintf("service %s did not shut down within %s", e.Service, e.Timeout)
+	}
+
+	return fmt.Sprintf("shutdown of service %s was aborted: %v", e.Service, e.Cause)
+}
+
+// Unwrap returns the cause of the shutdown timeout.
+func (e *ShutdownTimeoutError) Unwrap() error {
+	return e.Cause
+}
+
+// CrashLoopError is returned by Handle.Wait when a supervised service has exhausted its restart budget,
+// i.e. it would have been restarted more than Supervision.MaxRestarts times within Supervision.Window.
+type CrashLoopError struct {
+	// Service is the identity of the crash-looping service, as returned by Handle.String.
+	Service string
+	// Restarts is the number of restarts within Window.
+	Restarts int
+	// Window is the time window the restarts occurred in.
+	Window time.Duration
+	// Err is the error returned by the last run of the service, if any.
+	Err error
+}
+
+// Error returns a human-readable description of the crash loop.
+func (e *CrashLoopError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("service %s restarted %d times within %s: %v", e.Service, e.Restarts, e.Window, e.Err)
+	}
+
+	return fmt.Sprintf("service %s restarted %d times within %s", e.Service, e.Restarts, e.Window)
+}
+
+// Unwrap returns the error returned by the last run of the service.
+func (e *CrashLoopError) Unwrap() error {
+	return e.Err
+}
+
+// ShutdownRequestedError is the cause of the cancellation of the context a service is run with,
+// when the service is requested to shut down. It can be retrieved from the context using context.Cause.
+type ShutdownRequestedError struct {
+	// Service is the identity of the service requested to shut down, as returned by Handle.String.
+	Service string
+	// Restart reports whether the service is shut down in order to be restarted.
+	Restart bool
+}
+
+// Error returns a human-readable description of the shutdown request.
+func (e *ShutdownRequestedError) Error() string {
+	if e.Restart {
+		return fmt.Sprintf("service %s is restarting", e.Service)
+	}
+
+	return fmt.Sprintf("service %s is shutting down", e.Service)
+}
+
+// InitError is returned by Handle.Wait when Service.Initialize of a service returned an error or panicked.
+type InitError struct {
+	// Service is the identity of the service, as returned by Handle.String.
+	Service string
+	// Phase is the phase the error occurred in, which is always PhaseInitializing.
+	Phase Phase
+	// Duration is the time Initialize took before it failed.
+	Duration time.Duration
+	// Err is the error returned by Initialize, or a *PanicError if it panicked.
+	Err error
+}
+
+// Error returns a human-readable description of the initialization failure.
+func (e *InitError) Error() string {
+	return fmt.Sprintf("service %s failed to initialize: %v", e.Service, e.Err)
+}
+
+// Unwrap returns the error returned by Initialize.
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorExitCode returns the exit code of the error returned by Initialize.
+func (e *InitError) ErrorExitCode() int {
+	return fail.ExitCode(e.Err)
+}
+
+// RunError is returned by Handle.Wait when Service.Run of a service returned an error or panicked,
+// or when one of its background tasks failed, see Context.Go.
+type RunError struct {
+	// Service is the identity of the service, as returned by Handle.String.
+	Service string
+	// Phase is the phase the error occurred in, which is always PhaseRunning.
+	Phase Phase
+	// Duration is the time Run took before it returned.
+	Duration time.Duration
+	// Err is the error returned by Run, or a *PanicError if it panicked.
+	Err error
+}
+
+// Error returns a human-readable description of the run failure.
+func (e *RunError) Error() string {
+	return fmt.Sprintf("service %s failed: %v", e.Service, e.Err)
+}
+
+// Unwrap returns the error returned by Run.
+func (e *RunError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorExitCode returns the exit code of the error returned by Run.
+func (e *RunError) ErrorExitCode() int {
+	return fail.ExitCode(e.Err)
+}
+
+// ShutdownError is returned by Handle.Wait when a service failed to shut down, i.e. when draining,
+// Service.Shutdown or one of the functions registered using Context.OnShutdown returned an error or panicked.
+type ShutdownError struct {
+	// Service is the identity of the service, as returned by Handle.String.
+	Service string
+	// Phase is the phase the error occurred in, which is always PhaseShuttingDown.
+	Phase Phase
+	// Duration is the time the shutdown took.
+	Duration time.Duration
+	// Err is the error encountered while shutting down, which may be a *PanicError or a *ShutdownTimeoutError.
+	Err error
+}
+
+// Error returns a human-readable description of the shutdown failure.
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("service %s failed to shut down: %v", e.Service, e.Err)
+}
+
+// Unwrap returns the error encountered while shutting down.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorExitCode returns the exit code of the error encountered while shutting down.
+func (e *ShutdownError) ErrorExitCode() int {
+	return fail.ExitCode(e.Err)
+}
+
+// PanicError is the error a panic of a service is recovered as. It is usually wrapped by an InitError,
+// a RunError or a ShutdownError, and can be retrieved using errors.As.
+type PanicError struct {
+	// Service is the identity of the service, as returned by Handle.String.
+	Service string
+	// Phase is the phase the service panicked in.
+	Phase Phase
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked, as returned by debug.Stack.
+	Stack []byte
+}
+
+// newPanicError creates a PanicError for the given recovered value, capturing the current stack trace.
+// It must be called from the deferred function that recovered the panic.
+func newPanicError(service string, phase Phase, value any) *PanicError {
+	return &PanicError{
+		Service: service,
+		Phase:   phase,
+		Value:   value,
+		Stack:   debug.Stack(),
+	}
+}
+
+// Error returns a human-readable description of the panic.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("service %s panicked in phase %s: %v", e.Service, e.Phase, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error, or nil otherwise.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// ExitCategory returns ExitCategorySoftware, as a panic indicates an internal error of the service.
+func (e *PanicError) ExitCategory() ExitCategory {
+	return ExitCategorySoftware
+}
+
+// associatedError attaches associated errors to an error that is not a fail.Fail, see withAssociated.
+// Unlike fail.WithAssociated, it preserves the error, so that typed errors can still be retrieved using errors.As.
+type associatedError struct {
+	err        error
+	associated []error
+}
+
+// Error returns the message of the error.
+func (e *associatedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error and its associated errors.
+func (e *associatedError) Unwrap() []error {
+	return append([]error{e.err}, e.associated...)
+}
+
+// ErrorMessage returns the message of the error.
+func (e *associatedError) ErrorMessage() string {
+	return fail.Message(e.err)
+}
+
+// ErrorCauses returns the causes of the error.
+func (e *associatedError) ErrorCauses() []error {
+	return fail.Causes(e.err)
+}
+
+// ErrorAssociated returns the errors associated with the error.
+func (e *associatedError) ErrorAssociated() []error {
+	return append(fail.Associated(e.err), e.associated...)
+}
+
+// ErrorExitCode returns the exit code of the error.
+func (e *associatedError) ErrorExitCode() int {
+	return fail.ExitCode(e.err)
+}
+
+// ErrorAttributes returns the attributes of the error.
+func (e *associatedError) ErrorAttributes() map[string]any {
+	return fail.Attributes(e.err)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+// PhaseEvent describes the transition of a service to a new Phase.
+type PhaseEvent struct {
+	// Phase is the phase the service transitioned to.
+	Phase Phase
+	// Previous is the phase the service transitioned from.
+	Previous Phase
+	// Time is the time of the transition.
+	Time time.Time
+	// Error is the error associated with the transition, if any.
+	// It is usually set for transitions to PhaseFailed or PhaseRestarting.
+	Error error
+}
+
+// phaseWatcher is a callback registered using Handle.Watch.
+type phaseWatcher struct {
+	fn func(PhaseEvent)
+}
+
+// Watch registers a callback that is called for every phase transition of the service, in order.
+// Callbacks are called synchronously by the goroutine performing the transition and must therefore not block.
+// The returned function unregisters the callback; it is safe to call multiple times.
+func (h *Handle) Watch(fn func(PhaseEvent)) (unwatch func()) {
+	w := &phaseWatcher{fn: fn}
+
+	h.phaseMtx.Lock()
+	h.watchers = append(h.watchers, w)
+	h.phaseMtx.Unlock()
+
+	return func() {
+		h.unwatch(w)
+	}
+}
+
+// Events returns a channel that emits every phase transition of the service, in order.
+// No transitions are dropped, regardless of how slowly the channel is drained.
+// The channel is closed once the service has exited and all transitions have been emitted,
+// or once the provided context is done.
+func (h *Handle) Events(ctx context.Context) <-chan PhaseEvent {
+	var (
+		mtx    sync.Mutex
+		queue  []PhaseEvent
+		notify = make(chan struct{}, 1)
+		out    = make(chan PhaseEvent)
+	)
+
+	unwatch := h.Watch(func(e PhaseEvent) {
+		mtx.Lock()
+		queue = append(queue, e)
+		mtx.Unlock()
+
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	})
+
+	go func() {
+		defer close(out)
+		defer unwatch()
+
+		exited := h.exitSig
+		for {
+			mtx.Lock()
+			pending := queue
+			queue = nil
+			mtx.Unlock()
+
+			for _, e := range pending {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(pending) > 0 {
+				continue
+			}
+			if exited == nil {
+				// the service has exited and all transitions have been emitted
+				return
+			}
+
+			select {
+			case <-notify:
+			case <-exited:
+				// drain transitions that happened right before the exit
+				exited = nil
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// WaitForPhase blocks until the service is in the given phase.
+// It returns immediately if the service already is in the given phase.
+// Returns an error if the context is done, or if the service exits without reaching the phase.
+func (h *Handle) WaitForPhase(ctx context.Context, phase Phase) error {
+	reached := make(chan struct{})
+	var once sync.Once
+
+	unwatch := h.Watch(func(e PhaseEvent) {
+		if e.Phase == phase {
+			once.Do(func() {
+				close(reached)
+			})
+		}
+	})
+	defer unwatch()
+
+	if h.Phase() == phase {
+		return nil
+	}
+
+	select {
+	case <-reached:
+		return nil
+	case <-h.exitSig:
+		if h.Phase() == phase {
+			return nil
+		}
+
+		return fail.New().
+			Attribute("service", h.String()).
+			Attribute("phase", phase.String()).
+			Cause(h.Error()).
+			Msgf("service %s exited in phase %s without reaching phase %s", h, h.Phase(), phase)
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// unwatch unregisters the given watcher.
+func (h *Handle) unwatch(w *phaseWatcher) {
+	h.phaseMtx.Lock()
+	defer h.phaseMtx.Unlock()
+
+	for i, other := range h.watchers {
+		if other == w {
+			h.watchers = append(h.watchers[:i:i], h.watchers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/FlowSeer/fail"
+)
+
+//go:generate go tool golang.org/x/tools/cmd/stringer -type ExitCategory -trimprefix ExitCategory
+
+// ExitCategory classifies the failure of a service, so that it can be mapped to an exit code by an ExitPolicy.
+// The categories follow the conventions of sysexits.h.
+type ExitCategory int
+
+const (
+	// ExitCategoryNone indicates the failure has not been classified.
+	ExitCategoryNone ExitCategory = iota
+	// ExitCategoryUsage indicates the service was used incorrectly, e.g. with invalid arguments (EX_USAGE, 64).
+	ExitCategoryUsage
+	// ExitCategoryData indicates the input data of the service was invalid (EX_DATAERR, 65).
+	ExitCategoryData
+	// ExitCategoryUnavailable indicates a required service or resource was unavailable (EX_UNAVAILABLE, 69).
+	ExitCategoryUnavailable
+	// ExitCategorySoftware indicates an internal error of the service, e.g. a panic (EX_SOFTWARE, 70).
+	ExitCategorySoftware
+	// ExitCategoryIO indicates an error while reading or writing data (EX_IOERR, 74).
+	ExitCategoryIO
+	// ExitCategoryTemporary indicates a temporary failure, which may succeed when retried (EX_TEMPFAIL, 75).
+	ExitCategoryTemporary
+	// ExitCategoryPermission indicates the service lacked the permission to perform an operation (EX_NOPERM, 77).
+	ExitCategoryPermission
+	// ExitCategoryConfig indicates the service was misconfigured (EX_CONFIG, 78).
+	ExitCategoryConfig
+)
+
+// exitPolicyKey is the context key type for storing the ExitPolicy.
+type exitPolicyKey struct{}
+
+// ExitCategorizer can optionally be implemented by an error to define its ExitCategory.
+type ExitCategorizer interface {
+	// ExitCategory returns the category of the error.
+	ExitCategory() ExitCategory
+}
+
+// ExitRule decides the exit code of an error. It reports false if it does not apply to the error.
+type ExitRule = func(err error) (int, bool)
+
+// ExitPolicy decides the exit code of the process once the services run using RunAndExit,
+// RunParallelAndExit or RunGroupAndExit have exited.
+//
+// The exit code of each failed service is determined by the first of the following that applies:
+//  1. the first of Rules that applies to the error,
+//  2. an exit code explicitly set on the error or one of its causes, e.g. by fail.Builder.ExitCode or a *JobError,
+//  3. the code Categories maps the ExitCategory of the error to, see WithExitCategory,
+//  4. the code Phases maps the phase the service failed in to, i.e. PhaseInitializing for an *InitError,
+//     PhaseRunning for a *RunError, and PhaseShuttingDown for a *ShutdownError,
+//  5. Default.
+//
+// The exit codes of all failed services are combined into the exit code of the process using Combine.
+type ExitPolicy struct {
+	// Rules are custom rules that take precedence over all other mappings, applied in order.
+	Rules []ExitRule
+	// Categories maps the ExitCategory of an error to an exit code.
+	Categories map[ExitCategory]int
+	// Phases maps the phase a service failed in to an exit code.
+	Phases map[Phase]int
+	// Default is the exit code of errors no other mapping applies to.
+	// Values less than or equal to zero are treated as fail.DefaultExitCode.
+	Default int
+	// Combine combines the exit codes of all failed services into the exit code of the process.
+	// If nil, the highest exit code is used.
+	Combine func(codes []int) int
+}
+
+// CategorizedError marks an error with an ExitCategory, see WithExitCategory.
+type CategorizedError struct {
+	// Category is the category of the error.
+	Category ExitCategory
+	// Err is the categorized error.
+	Err error
+}
+
+// Error returns the message of the categorized error.
+func (e *CategorizedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the categorized error.
+func (e *CategorizedError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCategory returns the category of the error.
+func (e *CategorizedError) ExitCategory() ExitCategory {
+	return e.Category
+}
+
+// ErrorMessage returns the message of the categorized error, see fail.ErrorMessage.
+func (e *CategorizedError) ErrorMessage() string {
+	return fail.Message(e.Err)
+}
+
+// ErrorCauses returns the causes of the categorized error, see fail.ErrorCauses.
+func (e *CategorizedError) ErrorCauses() []error {
+	return fail.Causes(e.Err)
+}
+
+// ErrorAssociated returns the errors associated with the categorized error, see fail.ErrorAssociated.
+func (e *CategorizedError) ErrorAssociated() []error {
+	return fail.Associated(e.Err)
+}
+
+// ErrorExitCode returns the exit code of the categorized error, see fail.ErrorExitCode.
+func (e *CategorizedError) ErrorExitCode() int {
+	return fail.ExitCode(e.Err)
+}
+
+// ErrorAttributes returns the attributes of the categorized error, see fail.ErrorAttributes.
+func (e *CategorizedError) ErrorAttributes() map[string]any {
+	return fail.Attributes(e.Err)
+}
+
+// WithExitCategory marks the given error with the given category, e.g. to report a misconfiguration
+// using ExitCategoryConfig. Returns nil if err is nil.
+func WithExitCategory(err error, category ExitCategory) error {
+	if err == nil {
+		return nil
+	}
+
+	return &CategorizedError{Category: category, Err: err}
+}
+
+// ExitCategoryOf returns the category of the given error, i.e. the category of the first error in its chain
+// that implements ExitCategorizer, or ExitCategoryNone if there is none.
+func ExitCategoryOf(err error) ExitCategory {
+	if err == nil {
+		return ExitCategoryNone
+	}
+
+	var categorizer ExitCategorizer
+	if errors.As(err, &categorizer) {
+		return categorizer.ExitCategory()
+	}
+
+	// fail errors do not support errors.As, so their causes are inspected directly
+	for _, cause := range fail.Causes(err) {
+		if category := ExitCategoryOf(cause); category != ExitCategoryNone {
+			return category
+		}
+	}
+
+	return ExitCategoryNone
+}
+
+// DefaultExitPolicy returns the ExitPolicy used if none is configured.
+// Categories are mapped to the exit codes defined by sysexits.h, and the highest exit code of all
+// failed services is used. Uncategorized errors are mapped by the phase the service failed in:
+//   - PhaseInitializing: 78 (EX_CONFIG), as initialization usually fails due to misconfiguration,
+//   - PhaseShuttingDown: 70 (EX_SOFTWARE), as a service failing to shut down cleanly indicates an internal error.
+//
+// Errors returned by Run are mapped to fail.DefaultExitCode, as they are usually transient.
+func DefaultExitPolicy() ExitPolicy {
+	return ExitPolicy{
+		Categories: map[ExitCategory]int{
+			ExitCategoryUsage:       64,
+			ExitCategoryData:        65,
+			ExitCategoryUnavailable: 69,
+			ExitCategorySoftware:    70,
+			ExitCategoryIO:          74,
+			ExitCategoryTemporary:   75,
+			ExitCategoryPermission:  77,
+			ExitCategoryConfig:      78,
+		},
+		Phases: map[Phase]int{
+			PhaseInitializing: 78,
+			PhaseShuttingDown: 70,
+		},
+		Default: fail.DefaultExitCode,
+	}
+}
+
+// WithExitPolicy returns a new context with the specified ExitPolicy.
+// It is used by RunAndExit, RunParallelAndExit and RunGroupAndExit to decide the exit code of the process.
+func WithExitPolicy(ctx context.Context, policy ExitPolicy) context.Context {
+	return context.WithValue(ctx, exitPolicyKey{}, policy)
+}
+
+// ExitPolicyFromContext retrieves the ExitPolicy from the context.
+// If no ExitPolicy is set in the context, DefaultExitPolicy is returned.
+func ExitPolicyFromContext(ctx context.Context) ExitPolicy {
+	if policy, ok := ctx.Value(exitPolicyKey{}).(ExitPolicy); ok {
+		return policy
+	}
+
+	return DefaultExitPolicy()
+}
+
+// ExitCode returns the exit code of the process for the given errors of all services, where nil errors are ignored.
+// Returns 0 if no service has failed.
+func (p ExitPolicy) ExitCode(errs ...error) int {
+	var codes []int
+	for _, err := range errs {
+		if err != nil {
+			codes = append(codes, p.errorExitCode(err))
+		}
+	}
+
+	switch {
+	case len(codes) == 0:
+		return 0
+	case p.Combine != nil:
+		return p.Combine(codes)
+	default:
+		return slices.Max(codes)
+	}
+}
+
+// errorExitCode returns the exit code of a single, non-nil error.
+func (p ExitPolicy) errorExitCode(err error) int {
+	for _, rule := range p.Rules {
+		if code, ok := rule(err); ok {
+			return code
+		}
+	}
+
+	if code := fail.ExitCode(err); code > 0 && code != fail.DefaultExitCode {
+		return code
+	}
+
+	if code, ok := p.Categories[ExitCategoryOf(err)]; ok {
+		return code
+	}
+
+	if phase, ok := failedPhase(err); ok {
+		if code, ok := p.Phases[phase]; ok {
+			return code
+		}
+	}
+
+	if p.Default <= 0 {
+		return fail.DefaultExitCode
+	}
+
+	return p.Default
+}
+
+// failedPhase returns the phase a service failed in, based on the lifecycle error it returned.
+// It reports false if the error is not a lifecycle error.
+func failedPhase(err error) (Phase, bool) {
+	var initErr *InitError
+	if errors.As(err, &initErr) {
+		return initErr.Phase, true
+	}
+
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		return runErr.Phase, true
+	}
+
+	var shutdownErr *ShutdownError
+	if errors.As(err, &shutdownErr) {
+		return shutdownErr.Phase, true
+	}
+
+	return 0, false
+}
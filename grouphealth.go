@@ -0,0 +1,286 @@
+package service
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ServiceHealth is the health of a single service of a group, see AggregateHealth.
+type ServiceHealth struct {
+	// Service is the identity of the service, as returned by Handle.String.
+	Service string
+	// Name is the name of the service, see Handle.Name.
+	Name string
+	// Health is the health of the service.
+	Health Health
+}
+
+// HealthPolicy decides the health status of a group of services from the health of each service,
+// and returns a human-readable reason for it. It is only called with at least one service.
+// The predefined policies are AllHealthy, AnyHealthy, Quorum and Weighted.
+type HealthPolicy = func(services []ServiceHealth) (HealthStatus, string)
+
+// GroupHealth is the aggregated health of a group of services, see AggregateHealth.
+type GroupHealth struct {
+	// Status is the status of the group, as decided by the HealthPolicy.
+	Status HealthStatus
+	// Reason is the reason for the status of the group, as returned by the HealthPolicy.
+	Reason string
+	// Worst is the most severe status of any service of the group, where HealthStatusError is more severe than
+	// HealthStatusDegraded, HealthStatusUnknown, HealthStatusHealthy and HealthStatusShutdown, in that order.
+	Worst HealthStatus
+	// Services maps the identity of each service, as returned by Handle.String, to its Health.
+	Services map[string]Health
+	// Error is the error of the first failing service, ordered by identity, or nil if no service is failing.
+	Error error
+}
+
+// groupHealthJSON is the JSON representation of a GroupHealth.
+type groupHealthJSON struct {
+	Status   HealthStatus      `json:"status"`
+	Reason   string            `json:"reason,omitempty"`
+	Worst    HealthStatus      `json:"worst"`
+	Services map[string]Health `json:"services"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the GroupHealth as a JSON object with the fields status, reason, worst, services and error,
+// where the error is encoded as its message.
+func (g GroupHealth) MarshalJSON() ([]byte, error) {
+	j := groupHealthJSON{
+		Status:   g.Status,
+		Reason:   g.Reason,
+		Worst:    g.Worst,
+		Services: g.Services,
+	}
+	if g.Error != nil {
+		j.Error = g.Error.Error()
+	}
+
+	return json.Marshal(j)
+}
+
+// Health returns the GroupHealth as a Health, which reports the health of each service in the details.
+func (g GroupHealth) Health() Health {
+	return Health{
+		Status:  g.Status,
+		Reason:  g.Reason,
+		Details: g.Services,
+		Error:   g.Error,
+	}
+}
+
+// AggregateHealth aggregates the health of the services managed by the given handles using the given policy,
+// e.g. for the readiness endpoint of a process running multiple services using RunGroup.
+// If policy is nil, AllHealthy is used. A group without services has HealthStatusUnknown.
+func AggregateHealth(handles []*Handle, policy HealthPolicy) GroupHealth {
+	services := make([]ServiceHealth, len(handles))
+	for i, h := range handles {
+		services[i] = ServiceHealth{
+			Service: h.String(),
+			Name:    h.Name(),
+			Health:  h.Health(),
+		}
+	}
+
+	return aggregateServices(services, policy)
+}
+
+// Health returns the aggregated health of all services started by the runner using the given policy,
+// see AggregateHealth.
+func (r *Runner) Health(policy HealthPolicy) GroupHealth {
+	return AggregateHealth(r.Handles(), policy)
+}
+
+// aggregateServices aggregates the health of the given services using the given policy.
+func aggregateServices(services []ServiceHealth, policy HealthPolicy) GroupHealth {
+	if policy == nil {
+		policy = AllHealthy()
+	}
+
+	slices.SortFunc(services, func(a, b ServiceHealth) int {
+		return cmp.Compare(a.Service, b.Service)
+	})
+
+	g := GroupHealth{
+		Status:   HealthStatusUnknown,
+		Worst:    HealthStatusUnknown,
+		Services: make(map[string]Health, len(services)),
+	}
+	if len(services) == 0 {
+		return g
+	}
+
+	g.Worst = HealthStatusShutdown
+	for _, s := range services {
+		g.Services[s.Service] = s.Health
+
+		if healthSeverity(s.Health.Status) > healthSeverity(g.Worst) {
+			g.Worst = s.Health.Status
+		}
+		if s.Health.Status == HealthStatusError && g.Error == nil {
+			g.Error = s.Health.Error
+		}
+	}
+
+	g.Status, g.Reason = policy(services)
+	if g.Status != HealthStatusError {
+		g.Error = nil
+	}
+
+	return g
+}
+
+// healthSeverity returns the severity of the given status, where higher is more severe.
+func healthSeverity(status HealthStatus) int {
+	switch status {
+	case HealthStatusError:
+		return 4
+	case HealthStatusDegraded:
+		return 3
+	case HealthStatusUnknown:
+		return 2
+	case HealthStatusHealthy:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AllHealthy returns a HealthPolicy that requires all services to be healthy: the group is failing if any service
+// is failing, and degraded if any service is degraded or its health is unknown. Services that have been shut down
+// are ignored, unless all of them have been shut down.
+func AllHealthy() HealthPolicy {
+	return func(services []ServiceHealth) (HealthStatus, string) {
+		var failing, degraded, shutdown int
+		for _, s := range services {
+			switch s.Health.Status {
+			case HealthStatusError:
+				failing++
+			case HealthStatusDegraded, HealthStatusUnknown:
+				degraded++
+			case HealthStatusShutdown:
+				shutdown++
+			}
+		}
+
+		switch {
+		case failing > 0:
+			return HealthStatusError, fmt.Sprintf("%d of %d services failing", failing, len(services))
+		case degraded > 0:
+			return HealthStatusDegraded, fmt.Sprintf("%d of %d services degraded", degraded, len(services))
+		case shutdown == len(services):
+			return HealthStatusShutdown, ""
+		default:
+			return HealthStatusHealthy, ""
+		}
+	}
+}
+
+// AnyHealthy returns a HealthPolicy that requires at least one service to be healthy, e.g. for redundant services:
+// the group is healthy if any service is healthy, degraded if any service is degraded, and failing otherwise.
+// If all services have been shut down, the group has been shut down.
+func AnyHealthy() HealthPolicy {
+	return func(services []ServiceHealth) (HealthStatus, string) {
+		healthy, degraded, shutdown := countHealth(services)
+
+		switch {
+		case healthy > 0:
+			return HealthStatusHealthy, ""
+		case degraded > 0:
+			return HealthStatusDegraded, fmt.Sprintf("none of %d services healthy", len(services))
+		case shutdown == len(services):
+			return HealthStatusShutdown, ""
+		default:
+			return HealthStatusError, fmt.Sprintf("none of %d services available", len(services))
+		}
+	}
+}
+
+// Quorum returns a HealthPolicy that requires at least n services to be available: the group is healthy if all
+// services are healthy, degraded if at least n services are healthy or degraded, and failing otherwise.
+// Services that have been shut down are ignored, unless all of them have been shut down, in which case
+// the group has been shut down.
+func Quorum(n int) HealthPolicy {
+	return func(services []ServiceHealth) (HealthStatus, string) {
+		healthy, degraded, shutdown := countHealth(services)
+		active := len(services) - shutdown
+
+		switch {
+		case shutdown == len(services):
+			return HealthStatusShutdown, ""
+		case healthy == active:
+			return HealthStatusHealthy, ""
+		case healthy+degraded >= n:
+			return HealthStatusDegraded, fmt.Sprintf("%d of %d services healthy, quorum of %d reached", healthy, active, n)
+		default:
+			return HealthStatusError, fmt.Sprintf("%d of %d services available, quorum of %d not reached", healthy+degraded, active, n)
+		}
+	}
+}
+
+// Weighted returns a HealthPolicy that weighs services by importance: each service contributes its weight,
+// looked up by its name, if it is healthy, and half its weight if it is degraded. Services without a weight
+// have a weight of 1. The group is healthy if all services are healthy, degraded if the contributed share of
+// the total weight is at least threshold (between 0 and 1), and failing otherwise.
+// Services that have been shut down are ignored and do not count towards the total weight, unless all of them
+// have been shut down, in which case the group has been shut down.
+func Weighted(weights map[string]float64, threshold float64) HealthPolicy {
+	return func(services []ServiceHealth) (HealthStatus, string) {
+		healthy, _, shutdown := countHealth(services)
+
+		var total, score float64
+		for _, s := range services {
+			if s.Health.Status == HealthStatusShutdown {
+				continue
+			}
+
+			weight, ok := weights[s.Name]
+			if !ok {
+				weight = 1
+			}
+
+			total += weight
+			switch s.Health.Status {
+			case HealthStatusHealthy:
+				score += weight
+			case HealthStatusDegraded:
+				score += weight / 2
+			}
+		}
+
+		share := 0.0
+		if total > 0 {
+			share = score / total
+		}
+
+		switch {
+		case shutdown == len(services):
+			return HealthStatusShutdown, ""
+		case healthy == len(services)-shutdown:
+			return HealthStatusHealthy, ""
+		case share >= threshold:
+			return HealthStatusDegraded, fmt.Sprintf("weighted health %.2f, threshold %.2f reached", share, threshold)
+		default:
+			return HealthStatusError, fmt.Sprintf("weighted health %.2f, threshold %.2f not reached", share, threshold)
+		}
+	}
+}
+
+// countHealth returns the number of services that are healthy, degraded and have been shut down.
+func countHealth(services []ServiceHealth) (healthy, degraded, shutdown int) {
+	for _, s := range services {
+		switch s.Health.Status {
+		case HealthStatusHealthy:
+			healthy++
+		case HealthStatusDegraded:
+			degraded++
+		case HealthStatusShutdown:
+			shutdown++
+		}
+	}
+
+	return healthy, degraded, shutdown
+}
@@ -0,0 +1,242 @@
+// Package grpchealth implements the gRPC health checking protocol (grpc.health.v1) for services,
+// so that the health of services can be probed by gRPC clients, load balancers and service meshes.
+//
+// The health of each service is derived from its Handle: a service is SERVING if it is ready
+// (see service.Handle.Ready), and NOT_SERVING otherwise. Services are identified by their name.
+// The empty service name reports the overall health of all services, which is SERVING if all services
+// are ready or have finished successfully.
+//
+// Example usage:
+//
+//	handles := service.RunGroup(ctx, api, worker)
+//
+//	srv := grpc.NewServer()
+//	grpchealth.Register(srv, func() []*service.Handle { return handles })
+package grpchealth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Option is a function that modifies Options.
+// It is used to configure the Server.
+type Option = func(*Options)
+
+// Options holds options for the Server.
+type Options struct {
+	// PollInterval is the interval in which the health of services is re-evaluated for Watch,
+	// in addition to re-evaluating it on every phase transition of a service.
+	PollInterval time.Duration
+}
+
+// DefaultOptions returns an Options struct with default values.
+// By default, the health of services is polled every second.
+func DefaultOptions() *Options {
+	return &Options{
+		PollInterval: time.Second,
+	}
+}
+
+// WithPollInterval returns an Option that sets the interval in which the health of services is re-evaluated
+// for Watch. Non-positive intervals are ignored.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		if interval > 0 {
+			o.PollInterval = interval
+		}
+	}
+}
+
+// Server implements the grpc.health.v1.Health service, backed by the handles of services.
+// It is created using NewServer or Register.
+type Server struct {
+	healthpb.UnimplementedHealthServer
+
+	handles      func() []*service.Handle
+	pollInterval time.Duration
+
+	mtx sync.RWMutex
+	// shutdown is set once the server has been shut down, see Shutdown.
+	shutdown bool
+	// changed is closed and replaced whenever Shutdown or Resume is called, to wake up all watchers.
+	changed chan struct{}
+}
+
+// NewServer returns a Server reporting the health of the services returned by handles.
+// The handles are retrieved on every request, so that e.g. Runner.Handles can be used.
+func NewServer(handles func() []*service.Handle, opts ...Option) *Server {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Server{
+		handles:      handles,
+		pollInterval: o.PollInterval,
+		changed:      make(chan struct{}),
+	}
+}
+
+// Register creates a Server using NewServer and registers it with the given gRPC server.
+func Register(registrar grpc.ServiceRegistrar, handles func() []*service.Handle, opts ...Option) *Server {
+	s := NewServer(handles, opts...)
+	healthpb.RegisterHealthServer(registrar, s)
+
+	return s
+}
+
+// Check returns the serving status of the requested service.
+// Returns a NotFound error if no service with the requested name exists.
+func (s *Server) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := s.status(req.GetService())
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+// List returns the serving status of all services, including the overall health under the empty name.
+func (s *Server) List(_ context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	statuses := map[string]*healthpb.HealthCheckResponse{}
+	for _, name := range s.names() {
+		if st, ok := s.status(name); ok {
+			statuses[name] = &healthpb.HealthCheckResponse{Status: st}
+		}
+	}
+
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch streams the serving status of the requested service, starting with its current status
+// and followed by every change of it. If no service with the requested name exists, SERVICE_UNKNOWN is sent.
+// Changes are detected on every phase transition of a service, and by polling its health.
+func (s *Server) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	name := req.GetService()
+
+	notify := make(chan struct{}, 1)
+	watched := map[*service.Handle]func(){}
+	defer func() {
+		for _, unwatch := range watched {
+			unwatch()
+		}
+	}()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		// services may be added at any time, so new handles are watched as well
+		for _, h := range s.handles() {
+			if _, ok := watched[h]; !ok {
+				watched[h] = h.Watch(func(service.PhaseEvent) {
+					select {
+					case notify <- struct{}{}:
+					default:
+					}
+				})
+			}
+		}
+
+		st, ok := s.status(name)
+		if !ok {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return status.Error(codes.Canceled, "stream has ended")
+			}
+			last = st
+		}
+
+		s.mtx.RLock()
+		changed := s.changed
+		s.mtx.RUnlock()
+
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-notify:
+		case <-ticker.C:
+		case <-changed:
+		}
+	}
+}
+
+// Shutdown marks all services as NOT_SERVING, regardless of their health, e.g. while the process is
+// shutting down so that clients stop sending requests before the services have stopped.
+// It can be undone using Resume.
+func (s *Server) Shutdown() {
+	s.setShutdown(true)
+}
+
+// Resume reverts Shutdown, so that the serving status of services is derived from their health again.
+func (s *Server) Resume() {
+	s.setShutdown(false)
+}
+
+// setShutdown sets whether the server has been shut down and wakes up all watchers.
+func (s *Server) setShutdown(shutdown bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.shutdown = shutdown
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// status returns the serving status of the service with the given name, or the overall status
+// if the name is empty. It reports false if no service with the given name exists.
+func (s *Server) status(name string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	s.mtx.RLock()
+	shutdown := s.shutdown
+	s.mtx.RUnlock()
+
+	found := name == ""
+	serving := true
+	for _, h := range s.handles() {
+		if name == "" {
+			// services that have finished successfully do not affect the overall status
+			serving = serving && (h.Ready() || h.Phase() == service.PhaseFinished)
+			continue
+		}
+
+		if h.Name() == name {
+			found = true
+			serving = serving && h.Ready()
+		}
+	}
+
+	switch {
+	case !found:
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	case shutdown || !serving:
+		return healthpb.HealthCheckResponse_NOT_SERVING, true
+	default:
+		return healthpb.HealthCheckResponse_SERVING, true
+	}
+}
+
+// names returns the names of all services, including the empty name of the overall status.
+func (s *Server) names() []string {
+	names := []string{""}
+	seen := map[string]bool{"": true}
+	for _, h := range s.handles() {
+		if !seen[h.Name()] {
+			seen[h.Name()] = true
+			names = append(names, h.Name())
+		}
+	}
+
+	return names
+}
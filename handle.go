@@ -5,7 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/FlowSeer/fail"
 )
@@ -26,17 +29,74 @@ type Handle struct {
 	// phase is the current lifecycle phase/state of the service.
 	phase    Phase
 	phaseMtx sync.RWMutex
+	// watchers are notified about every phase transition, see Watch.
+	watchers []*phaseWatcher
+	// notifyMtx serializes phase transitions, so watchers are notified in order.
+	notifyMtx sync.Mutex
+	// svc is the service managed by the handle.
+	svc Service
+	// svcContext is the context the service is run with.
+	svcContext *Context
+	// lc is the current lifecycle of the service, or nil if no lifecycle has been started yet.
+	lc    *lifecycle
+	lcMtx sync.RWMutex
 	// exitSig is the channel singaling that the service has exited.
 	// It is closed when the service has exited either successfully or due to an error.
-	exitSig chan struct{}
-	// shutdownFunc is the function to gracefully shut down the service.
-	shutdownFunc func(context.Context) error
+	exitSig  chan struct{}
+	exitOnce sync.Once
+	// stopSig is closed once the service has been requested to stop permanently.
+	// No new lifecycle is started after it has been closed.
+	stopSig      chan struct{}
 	shutdownOnce sync.Once
+	// shutdownTimeout is the maximum duration the service may take to shut down.
+	shutdownTimeout time.Duration
+	// drainDelay is the time the service stays in PhaseDraining before it is shut down.
+	drainDelay time.Duration
+	// shutdownPolicy decides what happens when the service does not shut down within shutdownTimeout.
+	shutdownPolicy ShutdownTimeoutPolicy
+	// escalate is called with the ShutdownTimeoutError if shutdownPolicy is ShutdownTimeoutEscalate.
+	escalate func(error)
+	// supervisor decides whether and when the service is restarted.
+	supervisor *supervisor
+	// restarts is the number of times the service has been restarted.
+	restarts atomic.Int64
+	// started is set once the service has been running for the first time.
+	started atomic.Bool
+	// dependencies are the handles of the services this service depends on.
+	dependencies []*Handle
+
+	// controlMtx serializes reloads, pauses and resumes of the service.
+	controlMtx sync.Mutex
+	// lastReload is the outcome of the last reload of the service, or nil if it has not been reloaded yet.
+	lastReload atomic.Pointer[reloadResult]
 
 	shutdownErr    error
 	shutdownErrMtx sync.RWMutex
 }
 
+// lifecycle tracks a single Initialize, Run and Shutdown cycle of a service.
+// A new lifecycle is started every time the service is restarted.
+type lifecycle struct {
+	// ctx is the context the service is run with during this lifecycle.
+	// It is canceled once the service is requested to shut down, see ShutdownRequestedError.
+	ctx    *Context
+	cancel context.CancelCauseFunc
+	// runDone is closed once the service is no longer running,
+	// either because Run has returned or because it was never called.
+	runDone     chan struct{}
+	runDoneOnce sync.Once
+	// restart is set if the lifecycle was stopped in order to restart the service.
+	restart atomic.Bool
+	// abandoned is closed once the lifecycle has been abandoned, because it did not stop in time
+	// in order to restart the service, see Handle.abandonLifecycle.
+	abandoned   chan struct{}
+	abandonOnce sync.Once
+	abandonErr  error
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
 func (h *Handle) String() string {
 	if h.namespace != "" {
 		return fmt.Sprintf("%s/%s @ %s", h.Namespace(), h.Name(), h.Version())
@@ -70,19 +130,98 @@ func (h *Handle) Phase() Phase {
 	return h.getPhase()
 }
 
+// Health returns the current health of the service instance, as reported by the service.
+// If the service has registered health checks (see Context.RegisterCheck), the health of a running service
+// is combined with the aggregated health of its checks, see Context.CheckHealth.
+// If the last reload of the service failed, a healthy service is reported as degraded.
+func (h *Handle) Health() Health {
+	health := h.serviceHealth()
+
+	if lc := h.getLifecycle(); lc != nil && !lc.isRunDone() && lc.ctx.checks.len() > 0 {
+		health = withCheckHealth(health, lc.ctx.checks.health())
+	}
+
+	return health
+}
+
+// serviceHealth returns the current health of the service instance without its health checks.
+// If the last reload of the service failed, a healthy service is reported as degraded.
+func (h *Handle) serviceHealth() Health {
+	health := h.svc.Health()
+
+	if _, err := h.LastReload(); err != nil && health.Status == HealthStatusHealthy {
+		return Health{
+			Status:  HealthStatusDegraded,
+			Reason:  "reload failed",
+			Details: health.Details,
+			Error:   err,
+		}
+	}
+
+	return health
+}
+
+// Ready reports whether the service instance is ready to accept work, i.e. it is running and
+// healthy or degraded. A service that is draining is not ready anymore.
+func (h *Handle) Ready() bool {
+	phase := h.Phase()
+	if phase != PhaseRunning && phase != PhaseReloading {
+		return false
+	}
+
+	status := h.Health().Status
+	return status == HealthStatusHealthy || status == HealthStatusDegraded
+}
+
+// Live reports whether the service instance is alive, i.e. it has not exited and does not report an error.
+// A service that is draining or shutting down is still live. The health checks of the service
+// (see Context.RegisterCheck) are not taken into account, so that a failing dependency does not
+// make the service appear dead.
+func (h *Handle) Live() bool {
+	return !h.hasExited() && h.serviceHealth().Status != HealthStatusError
+}
+
+// Started reports whether the service instance has started, i.e. it has been running at least once.
+// A service that has been restarted or has exited since is still considered started.
+func (h *Handle) Started() bool {
+	return h.started.Load()
+}
+
+// Restarts returns the number of times the service instance has been restarted.
+func (h *Handle) Restarts() int {
+	return int(h.restarts.Load())
+}
+
+// ShutdownTimeout returns the maximum duration the service may take to shut down.
+func (h *Handle) ShutdownTimeout() time.Duration {
+	return h.shutdownTimeout
+}
+
 // Wait blocks until the service has exited.
 // It returns the last error encountered by the service, or nil if no error has occurred.
+// Depending on where the service failed, the error is a *InitError, *RunError or *ShutdownError,
+// which can be retrieved using errors.As, like a *PanicError if the service panicked.
+// If the service did not shut down in time, a *ShutdownTimeoutError is returned.
 func (h *Handle) Wait() error {
 	<-h.exitSig
 
-	return fail.WithAssociated(h.Error(), h.getShutdownErr())
+	return h.Error()
 }
 
 // Shutdown attempts to gracefully shut down the service instance, using the provided context for cancellation and timeout.
+// The service is not restarted anymore, regardless of its restart policy.
+// Shutdown returns once the service has exited, the shutdown timeout of the service has elapsed,
+// or the context is done, whichever happens first. In the latter two cases, a *ShutdownTimeoutError is returned
+// and the configured ShutdownTimeoutPolicy is applied.
 // Returns an error if shutdown fails or if the context is canceled or times out.
 func (h *Handle) Shutdown(ctx context.Context) error {
 	h.shutdownOnce.Do(func() {
-		if err := h.shutdownFunc(ctx); err != nil {
+		h.lcMtx.Lock()
+		close(h.stopSig)
+		lc := h.lc
+		h.lcMtx.Unlock()
+
+		if err := h.stopLifecycle(ctx, lc, h.exitSig, false); err != nil {
 			h.setShutdownErr(err)
 		}
 	})
@@ -90,9 +229,301 @@ func (h *Handle) Shutdown(ctx context.Context) error {
 	return h.getShutdownErr()
 }
 
+// Restart restarts the service instance, e.g. to recover a stuck service without restarting the process.
+// The current lifecycle is stopped like in Shutdown, and the service is initialized and run again right away,
+// reusing the same Handle. Manual restarts are performed regardless of the restart policy of the service
+// and do not count towards its restart budget, see Supervision.
+// If the service does not shut down within its shutdown timeout, the ShutdownTimeoutPolicy is applied like in Shutdown,
+// but the Handle is not marked as failed: unless the process exits, the current lifecycle is abandoned instead,
+// i.e. its goroutines are left running in the background, the functions registered using Context.OnShutdown
+// are called, and the service is initialized again while its abandoned Run may still be running.
+// Services created using Simple, the Builder, Job, Periodic and Cron support this; other services may fail to
+// initialize, in which case Restart returns their error.
+// Restart returns once the service is running again, using the provided context for cancellation and timeout.
+// Returns ErrServiceNotRunning if the service is not running, or an error if the service fails to restart.
+func (h *Handle) Restart(ctx context.Context) error {
+	lc := h.getLifecycle()
+	if lc == nil || lc.isRunDone() || h.isStopping() {
+		return ErrServiceNotRunning
+	}
+
+	// the outcome of the restart is the first transition to PhaseRunning or PhaseFailed after PhaseRestarting
+	result := make(chan error, 1)
+	restarting := false
+	report := func(err error) {
+		restarting = false
+		select {
+		case result <- err:
+		default:
+		}
+	}
+	unwatch := h.Watch(func(e PhaseEvent) {
+		switch {
+		case e.Phase == PhaseRestarting:
+			restarting = true
+		case restarting && e.Phase == PhaseRunning:
+			report(nil)
+		case restarting && e.Phase == PhaseFailed:
+			report(e.Error)
+		}
+	})
+	defer unwatch()
+
+	h.svcContext.Info("Restart requested")
+	lc.restart.Store(true)
+
+	// errors of the stopped lifecycle are reported when the service transitions to PhaseRestarting
+	_ = h.stopLifecycle(ctx, lc, lc.runDone, false)
+
+	select {
+	case err := <-result:
+		return err
+	case <-h.exitSig:
+		return fail.New().
+			Attribute("service", h.String()).
+			Cause(h.Error()).
+			Msgf("service %s exited instead of restarting", h)
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// stopLifecycle cancels the context of the given lifecycle and calls Service.Shutdown, at most once per lifecycle, and waits for done
+// to be closed. If cleanup is set, the functions registered using Context.OnShutdown are called afterwards.
+// It is bounded by the shutdown timeout of the service and the provided context.
+// If lc is nil, stopLifecycle only waits for done.
+func (h *Handle) stopLifecycle(ctx context.Context, lc *lifecycle, done <-chan struct{}, cleanup bool) error {
+	shutdownCtx, cancel := h.shutdownContext(ctx)
+	defer cancel()
+
+	sig := make(chan error, 1)
+	go func() {
+		var err error
+		if lc != nil {
+			lc.shutdownOnce.Do(func() {
+				drainErr := h.drain(shutdownCtx, lc)
+
+				lc.cancel(&ShutdownRequestedError{
+					Service: h.String(),
+					Restart: lc.restart.Load(),
+				})
+				lc.shutdownErr = withAssociated(h.shutdownService(lc.ctx.withContext(shutdownCtx)), drainErr)
+			})
+			err = lc.shutdownErr
+		}
+
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			return
+		}
+
+		if cleanup && lc != nil {
+			err = withAssociated(err, lc.ctx.cleanups.run(lc.ctx.withContext(shutdownCtx)))
+		}
+		sig <- err
+	}()
+
+	select {
+	case err := <-sig:
+		return err
+	case <-shutdownCtx.Done():
+		if lc != nil && lc.restart.Load() && !h.isStopping() {
+			return h.restartTimedOut(shutdownCtx, lc)
+		}
+
+		return h.shutdownTimedOut(shutdownCtx)
+	}
+}
+
+// shutdownService calls Service.Shutdown, recovering a panic as a *PanicError.
+func (h *Handle) shutdownService(ctx *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(h.String(), PhaseShuttingDown, r)
+		}
+	}()
+
+	return h.svc.Shutdown(ctx)
+}
+
+// shutdownContext returns a context for shutting down the service.
+// The returned context carries the values of the service context, is canceled when the provided context is done,
+// and is bounded by the shutdown timeout of the service.
+// The service context itself may already be canceled, so its cancellation is not inherited.
+func (h *Handle) shutdownContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	shutdownCtx, cancelCause := context.WithCancelCause(context.WithoutCancel(h.svcContext.Context))
+	stop := context.AfterFunc(ctx, func() {
+		cancelCause(context.Cause(ctx))
+	})
+
+	shutdownCtx, cancelTimeout := context.WithTimeout(shutdownCtx, h.shutdownTimeout)
+
+	return shutdownCtx, func() {
+		cancelTimeout()
+		stop()
+		cancelCause(nil)
+	}
+}
+
+// shutdownTimedOut creates a ShutdownTimeoutError for the given, done shutdown context
+// and applies the shutdown timeout policy.
+func (h *Handle) shutdownTimedOut(shutdownCtx context.Context) error {
+	err := h.shutdownTimeoutError(shutdownCtx)
+	h.applyShutdownTimeoutPolicy(err)
+
+	// the service is abandoned, its goroutines are left running in the background
+	h.setPhase(PhaseFailed, err)
+	h.setStopped(err)
+
+	return err
+}
+
+// restartTimedOut creates a ShutdownTimeoutError for the given, done shutdown context of a lifecycle
+// that was stopped in order to restart the service, and applies the shutdown timeout policy.
+// Unlike shutdownTimedOut, only the lifecycle is abandoned, so that the service is restarted, see abandonLifecycle.
+func (h *Handle) restartTimedOut(shutdownCtx context.Context, lc *lifecycle) error {
+	err := h.shutdownTimeoutError(shutdownCtx)
+	h.applyShutdownTimeoutPolicy(err)
+	h.abandonLifecycle(lc, err)
+
+	return err
+}
+
+// shutdownTimeoutError creates a ShutdownTimeoutError for the given, done shutdown context and logs it.
+func (h *Handle) shutdownTimeoutError(shutdownCtx context.Context) *ShutdownTimeoutError {
+	err := &ShutdownTimeoutError{
+		Service: h.String(),
+		Timeout: h.shutdownTimeout,
+		Cause:   context.Cause(shutdownCtx),
+	}
+
+	if errors.Is(err.Cause, context.DeadlineExceeded) {
+		h.svcContext.Error("Shutdown timed out", "timeout", h.shutdownTimeout.String())
+	} else {
+		h.svcContext.Error("Shutdown canceled")
+	}
+
+	return err
+}
+
+// applyShutdownTimeoutPolicy applies the shutdown timeout policy of the service for the given error.
+func (h *Handle) applyShutdownTimeoutPolicy(err *ShutdownTimeoutError) {
+	switch h.shutdownPolicy {
+	case ShutdownTimeoutForceExit:
+		fail.PrintPretty(err)
+		os.Exit(ExitPolicyFromContext(h.svcContext).ExitCode(err))
+	case ShutdownTimeoutEscalate:
+		if h.escalate != nil {
+			h.escalate(err)
+		}
+	}
+}
+
+// abandonLifecycle abandons the given lifecycle, which did not stop in time in order to restart the service,
+// with the given error. Its goroutines are left running in the background, while the functions registered using
+// Context.OnShutdown are called right away, bounded by the shutdown timeout of the service.
+// The lifecycle then ends with the error without waiting for the service any longer, so that it is restarted.
+func (h *Handle) abandonLifecycle(lc *lifecycle, err error) {
+	lc.abandonOnce.Do(func() {
+		h.svcContext.Warn("Abandoning the current lifecycle to restart", "error", err)
+
+		shutdownCtx, cancel := h.shutdownContext(context.Background())
+		defer cancel()
+
+		lc.abandonErr = withAssociated(err, lc.ctx.cleanups.run(lc.ctx.withContext(shutdownCtx)))
+		close(lc.abandoned)
+	})
+}
+
+// beginLifecycle starts a new lifecycle of the service and makes it the current one.
+// It returns false if the service has been requested to stop, in which case no lifecycle is started.
+func (h *Handle) beginLifecycle() (*lifecycle, bool) {
+	h.lcMtx.Lock()
+	defer h.lcMtx.Unlock()
+
+	if h.isStopping() {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithCancelCause(h.svcContext.Context)
+	lcCtx := h.svcContext.withContext(ctx)
+	lcCtx.tasks = newTaskGroup(cancel)
+	lcCtx.cleanups = &cleanupRegistry{}
+	lcCtx.checks = newCheckRegistry(ctx)
+
+	h.lc = &lifecycle{
+		ctx:       lcCtx,
+		cancel:    cancel,
+		runDone:   make(chan struct{}),
+		abandoned: make(chan struct{}),
+	}
+
+	return h.lc, true
+}
+
+// abortLifecycle cleans up the given lifecycle after the service failed to initialize, without calling Service.Shutdown.
+// It waits for the background tasks of the lifecycle and calls the functions registered using Context.OnShutdown,
+// bounded by the shutdown timeout of the service.
+func (h *Handle) abortLifecycle(lc *lifecycle) error {
+	shutdownCtx, cancel := h.shutdownContext(context.Background())
+	defer cancel()
+
+	select {
+	case <-lc.ctx.tasks.wait():
+	case <-shutdownCtx.Done():
+	}
+
+	return withAssociated(lc.ctx.tasks.err(), lc.ctx.cleanups.run(lc.ctx.withContext(shutdownCtx)))
+}
+
+// getLifecycle returns the current lifecycle of the service, or nil if none has been started yet.
+func (h *Handle) getLifecycle() *lifecycle {
+	h.lcMtx.RLock()
+	defer h.lcMtx.RUnlock()
+
+	return h.lc
+}
+
+// requestRestart stops the current lifecycle of the service in the background, causing it to be restarted.
+// It does nothing if the service is not running.
+func (h *Handle) requestRestart() {
+	lc := h.getLifecycle()
+	if lc == nil || lc.isRunDone() || h.isStopping() {
+		return
+	}
+
+	lc.restart.Store(true)
+	go func() {
+		_ = h.stopLifecycle(context.Background(), lc, lc.runDone, false)
+	}()
+}
+
+// hasExited reports whether the service has exited.
+func (h *Handle) hasExited() bool {
+	select {
+	case <-h.exitSig:
+		return true
+	default:
+		return false
+	}
+}
+
+// isStopping reports whether the service has been requested to stop permanently.
+func (h *Handle) isStopping() bool {
+	select {
+	case <-h.stopSig:
+		return true
+	default:
+		return false
+	}
+}
+
 func (h *Handle) setStopped(err error) {
-	h.setError(err)
-	close(h.exitSig)
+	h.exitOnce.Do(func() {
+		h.setError(err)
+		close(h.exitSig)
+	})
 }
 
 func (h *Handle) getPhase() Phase {
@@ -102,11 +533,47 @@ func (h *Handle) getPhase() Phase {
 	return h.phase
 }
 
-func (h *Handle) setPhase(phase Phase) {
+// setPhase transitions the service to the given phase and notifies all watchers.
+// The error is associated with the transition, and may be nil.
+func (h *Handle) setPhase(phase Phase, err error) {
+	h.transitionPhase(phase, err, nil)
+}
+
+// compareAndSetPhase transitions the service to the given phase like setPhase,
+// but only if it currently is in the old phase. It reports whether the transition took place.
+func (h *Handle) compareAndSetPhase(old, phase Phase, err error) bool {
+	return h.transitionPhase(phase, err, func(current Phase) bool {
+		return current == old
+	})
+}
+
+// transitionPhase transitions the service to the given phase and notifies all watchers,
+// if cond is nil or reports true for the current phase. It reports whether the transition took place.
+func (h *Handle) transitionPhase(phase Phase, err error, cond func(Phase) bool) bool {
+	h.notifyMtx.Lock()
+	defer h.notifyMtx.Unlock()
+
 	h.phaseMtx.Lock()
-	defer h.phaseMtx.Unlock()
+	if cond != nil && !cond(h.phase) {
+		h.phaseMtx.Unlock()
+		return false
+	}
 
+	event := PhaseEvent{
+		Phase:    phase,
+		Previous: h.phase,
+		Time:     time.Now(),
+		Error:    err,
+	}
 	h.phase = phase
+	watchers := slices.Clone(h.watchers)
+	h.phaseMtx.Unlock()
+
+	for _, w := range watchers {
+		w.fn(event)
+	}
+
+	return true
 }
 
 func (h *Handle) getError() error {
@@ -137,63 +604,80 @@ func (h *Handle) setShutdownErr(err error) {
 	h.shutdownErr = err
 }
 
+func (lc *lifecycle) setRunDone() {
+	lc.runDoneOnce.Do(func() {
+		close(lc.runDone)
+	})
+}
+
+// isAbandoned reports whether the lifecycle has been abandoned, see Handle.abandonLifecycle.
+func (lc *lifecycle) isAbandoned() bool {
+	select {
+	case <-lc.abandoned:
+		return true
+	default:
+		return false
+	}
+}
+
+// await calls fn in a new goroutine and returns its error once it returns, or nil as soon as the lifecycle
+// has been abandoned, leaving fn running in the background.
+func (lc *lifecycle) await(fn func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- fn()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-lc.abandoned:
+		return nil
+	}
+}
+
+func (lc *lifecycle) isRunDone() bool {
+	select {
+	case <-lc.runDone:
+		return true
+	default:
+		return false
+	}
+}
+
 func createErrorHandle(svc Service, err error) *Handle {
 	h := &Handle{
 		name:      svc.Name(),
 		namespace: svc.Namespace(),
 		version:   svc.Version(),
+		svc:       svc,
 		err:       err,
+		phase:     PhaseFailed,
 		exitSig:   make(chan struct{}),
+		stopSig:   make(chan struct{}),
 	}
 	// call with noop to forbid double-shutdown
 	h.shutdownOnce.Do(func() {})
-	close(h.exitSig)
+	h.exitOnce.Do(func() {
+		close(h.exitSig)
+	})
 
 	return h
 }
 
-func createHandle(svc Service, svcContext *Context) *Handle {
+func createHandle(svc Service, svcContext *Context, sup *supervisor, escalate func(error)) *Handle {
 	return &Handle{
-		name:      svc.Name(),
-		namespace: svc.Namespace(),
-		version:   svc.Version(),
-		exitSig:   make(chan struct{}),
-		shutdownFunc: func(ctx context.Context) error {
-			sig := make(chan struct{})
-
-			go func() {
-				for {
-					select {
-					case <-ctx.Done():
-						e := ctx.Err()
-						switch {
-						case errors.Is(e, context.Canceled):
-							svcContext.Error("Shutdown canceled")
-						case errors.Is(e, context.DeadlineExceeded):
-							svcContext.Error("Shutdown timed out")
-						}
-
-						os.Exit(fail.ExitCode(e))
-					case <-sig:
-						return
-					}
-				}
-			}()
-
-			var errs []error
-
-			if err := svc.Shutdown(svcContext); err != nil {
-				errs = append(errs, err)
-			}
-			if err := svcContext.meterShutdown(ctx); err != nil {
-				errs = append(errs, err)
-			}
-			if err := svcContext.tracerShutdown(ctx); err != nil {
-				errs = append(errs, err)
-			}
-
-			close(sig)
-			return fail.WrapMany("Shutdown encountered an error", errs...)
-		},
+		name:            svc.Name(),
+		namespace:       svc.Namespace(),
+		version:         svc.Version(),
+		svc:             svc,
+		svcContext:      svcContext,
+		exitSig:         make(chan struct{}),
+		stopSig:         make(chan struct{}),
+		shutdownTimeout: shutdownTimeout(svcContext, svc),
+		drainDelay:      drainDelay(svcContext, svc),
+		shutdownPolicy:  ShutdownTimeoutPolicyFromContext(svcContext),
+		escalate:        escalate,
+		supervisor:      sup,
 	}
 }
@@ -1,5 +1,7 @@
 package service
 
+import "encoding/json"
+
 // Health represents the current health status of a service or component.
 // It provides both machine-readable and human-readable information, making it suitable
 // for monitoring, diagnostics, and external health checks.
@@ -18,3 +20,26 @@ type Health struct {
 	// It should be set if the health status is due to an error condition, or nil otherwise.
 	Error error
 }
+
+// healthJSON is the JSON representation of a Health.
+type healthJSON struct {
+	Status  HealthStatus `json:"status"`
+	Reason  string       `json:"reason,omitempty"`
+	Details any          `json:"details,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the Health as a JSON object with the fields status, reason, details and error,
+// where the error is encoded as its message and empty fields are omitted.
+func (h Health) MarshalJSON() ([]byte, error) {
+	j := healthJSON{
+		Status:  h.Status,
+		Reason:  h.Reason,
+		Details: h.Details,
+	}
+	if h.Error != nil {
+		j.Error = h.Error.Error()
+	}
+
+	return json.Marshal(j)
+}
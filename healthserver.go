@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// HealthAddrEnvVar is the environment variable name for the address of the health server, e.g. ":8081".
+	// If it is set, the health server is enabled, see HealthServer.
+	HealthAddrEnvVar = "HEALTH_ADDR"
+)
+
+// healthServerKey is the context key type for storing the HealthServerOptions.
+type healthServerKey struct{}
+
+// HealthServerOption is a function that modifies HealthServerOptions.
+// It is used to configure the health server.
+type HealthServerOption = func(*HealthServerOptions)
+
+// HealthServerOptions holds options for the health server, which serves the health of all services of a runner
+// over HTTP using the endpoints /livez, /readyz and /startupz, see HealthHandler.
+type HealthServerOptions struct {
+	// Addr is the TCP address the health server listens on, e.g. ":8081".
+	// If empty, it is read using HealthAddrFromEnv with the EnvPrefix of the RunOptions, see WithEnvPrefix.
+	Addr string
+	// DegradedStatusCode is the HTTP status code of a passing probe if any service is degraded.
+	DegradedStatusCode int
+	// ErrorStatusCode is the HTTP status code of a failing probe, or of a passing probe if any service
+	// reports an error.
+	ErrorStatusCode int
+	// Policy decides the aggregated health of all services reported by the endpoints, and thereby whether
+	// the probes pass, see HealthHandler. If nil, AllHealthy is used, i.e. all services must pass.
+	Policy HealthPolicy
+}
+
+// DefaultHealthServerOptions returns a HealthServerOptions struct with default values.
+// By default, the address is read from the environment, degraded services are reported with 200 OK
+// and errors with 503 Service Unavailable.
+func DefaultHealthServerOptions() *HealthServerOptions {
+	return &HealthServerOptions{
+		DegradedStatusCode: http.StatusOK,
+		ErrorStatusCode:    http.StatusServiceUnavailable,
+	}
+}
+
+// WithHealthAddr returns a HealthServerOption that sets the address the health server listens on.
+func WithHealthAddr(addr string) HealthServerOption {
+	return func(o *HealthServerOptions) {
+		o.Addr = addr
+	}
+}
+
+// WithDegradedStatusCode returns a HealthServerOption that sets the HTTP status code of a passing probe
+// if any service is degraded.
+func WithDegradedStatusCode(code int) HealthServerOption {
+	return func(o *HealthServerOptions) {
+		o.DegradedStatusCode = code
+	}
+}
+
+// WithErrorStatusCode returns a HealthServerOption that sets the HTTP status code of a failing probe,
+// or of a passing probe if any service reports an error.
+func WithErrorStatusCode(code int) HealthServerOption {
+	return func(o *HealthServerOptions) {
+		o.ErrorStatusCode = code
+	}
+}
+
+// WithHealthPolicy returns a HealthServerOption that sets the policy deciding the aggregated health of all services,
+// and thereby whether the probes pass.
+func WithHealthPolicy(policy HealthPolicy) HealthServerOption {
+	return func(o *HealthServerOptions) {
+		o.Policy = policy
+	}
+}
+
+// HealthAddrFromEnv retrieves the address of the health server from the environment variable {PREFIX}_HEALTH_ADDR.
+// Returns an empty string if the variable is not set.
+func HealthAddrFromEnv(prefix string) string {
+	return GetEnv(prefix, HealthAddrEnvVar)
+}
+
+// WithHealthServer returns a new context that enables the health server, configured using the given options.
+// A runner using the returned context serves the health of its services until Wait returns, see HealthHandler.
+// The health server is bound once per process and address: runners started while it is serving are registered
+// with it, so that it serves the health of the services of all of them.
+func WithHealthServer(ctx context.Context, opts ...HealthServerOption) context.Context {
+	o := DefaultHealthServerOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return context.WithValue(ctx, healthServerKey{}, o)
+}
+
+// HealthServer retrieves the HealthServerOptions from the context.
+// If no options are set in the context, DefaultHealthServerOptions is used.
+// If no address is set, it is read using HealthAddrFromEnv with the EnvPrefix of the RunOptions of the context.
+// The boolean reports whether the health server is enabled for the context, i.e. it has an address.
+func HealthServer(ctx context.Context) (*HealthServerOptions, bool) {
+	o, ok := ctx.Value(healthServerKey{}).(*HealthServerOptions)
+	if !ok {
+		o = DefaultHealthServerOptions()
+	}
+	if o == nil {
+		// the health server has been disabled explicitly, e.g. for the children of a composite
+		return nil, false
+	}
+
+	c := *o
+	if c.Addr == "" {
+		c.Addr = HealthAddrFromEnv(runnerEnvPrefix(ctx))
+	}
+
+	return &c, c.Addr != ""
+}
+
+// HealthHandler returns an http.Handler serving the health of the services returned by handles.
+// It serves the following endpoints:
+//   - /livez: a service passes if it is live, see Handle.Live, regardless of its health checks,
+//   - /readyz: a service passes if it is ready, see Handle.Ready,
+//   - /startupz: a service passes if it has started, see Handle.Started.
+//
+// A service that does not pass is reported as HealthStatusError, and a service that has exited successfully
+// as HealthStatusShutdown. The healths of all services are then aggregated using the configured HealthPolicy,
+// e.g. Quorum, which decides whether the probe passes: it fails if the aggregated health is failing.
+//
+// Each endpoint responds with the aggregated Health as JSON, which contains the Health of each service in its
+// details. A failing probe responds with the ErrorStatusCode, a degraded one with the DegradedStatusCode,
+// and any other with 200 OK.
+func HealthHandler(handles func() []*Handle, opts ...HealthServerOption) http.Handler {
+	o := DefaultHealthServerOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return newHealthHandler(handles, o)
+}
+
+// newHealthHandler returns the http.Handler of HealthHandler using the given options.
+func newHealthHandler(handles func() []*Handle, opts *HealthServerOptions) http.Handler {
+	mux := http.NewServeMux()
+	// liveness must not depend on the health checks of services, which check their dependencies
+	mux.Handle("/livez", healthProbe(handles, opts, "live", (*Handle).Live, (*Handle).serviceHealth))
+	mux.Handle("/readyz", healthProbe(handles, opts, "ready", (*Handle).Ready, (*Handle).Health))
+	mux.Handle("/startupz", healthProbe(handles, opts, "started", (*Handle).Started, (*Handle).Health))
+
+	return mux
+}
+
+// healthProbe returns an http.Handler responding with the health of the services returned by handles,
+// as returned by health, where each service that does not pass the check is reported as failing, see HealthHandler.
+func healthProbe(handles func() []*Handle, opts *HealthServerOptions, name string, check func(*Handle) bool, health func(*Handle) Health) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		hs := handles()
+
+		services := make([]ServiceHealth, len(hs))
+		for i, h := range hs {
+			sh := health(h)
+
+			switch {
+			case h.hasExited() && h.Error() == nil:
+				sh.Status = HealthStatusShutdown
+			case !check(h):
+				sh = Health{
+					Status:  HealthStatusError,
+					Reason:  "not " + name,
+					Details: sh.Details,
+					Error:   sh.Error,
+				}
+			}
+
+			services[i] = ServiceHealth{Service: h.String(), Name: h.Name(), Health: sh}
+		}
+
+		health := aggregateServices(services, opts.Policy).Health()
+
+		code := http.StatusOK
+		switch health.Status {
+		case HealthStatusError:
+			code = opts.ErrorStatusCode
+		case HealthStatusDegraded:
+			code = opts.DegradedStatusCode
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+
+		if req.Method != http.MethodHead {
+			_ = json.NewEncoder(w).Encode(health)
+		}
+	})
+}
+
+// healthServers holds the health servers of the process by address. A health server is shared by all runners
+// serving their health on the same address, as the address can only be bound once.
+var (
+	healthServersMtx sync.Mutex
+	healthServers    = map[string]*healthServer{}
+)
+
+// healthServer is a health server serving the health of the services of all runners registered with it.
+type healthServer struct {
+	addr    string
+	server  *http.Server
+	runners []*Runner
+}
+
+// serveHealth serves the health of the services of the runner using the given options,
+// until Wait has finished waiting for all services.
+// If a health server is already serving on the address, the runner is registered with it instead,
+// in which case the options of the runner that started the server apply.
+func (r *Runner) serveHealth(opts *HealthServerOptions) {
+	logger := serviceLogger(r.ctx, runnerEnvPrefix(r.ctx))
+
+	healthServersMtx.Lock()
+	if s, ok := healthServers[opts.Addr]; ok {
+		s.runners = append(s.runners, r)
+		healthServersMtx.Unlock()
+
+		logger.Debug("Serving health using the existing health server", "addr", opts.Addr)
+		<-r.done
+		s.unregister(r)
+		return
+	}
+
+	listener, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		healthServersMtx.Unlock()
+		logger.Error("Failed to start health server", "addr", opts.Addr, "error", err)
+		return
+	}
+
+	s := &healthServer{
+		addr:    opts.Addr,
+		runners: []*Runner{r},
+	}
+	s.server = &http.Server{
+		Handler:           newHealthHandler(s.handles, opts),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	healthServers[opts.Addr] = s
+	healthServersMtx.Unlock()
+
+	go func() {
+		<-r.done
+		s.unregister(r)
+	}()
+
+	logger.Info("Serving health", "addr", listener.Addr().String())
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Health server failed", "addr", opts.Addr, "error", err)
+	}
+}
+
+// handles returns the handles of all services of the runners registered with the health server.
+func (s *healthServer) handles() []*Handle {
+	healthServersMtx.Lock()
+	runners := slices.Clone(s.runners)
+	healthServersMtx.Unlock()
+
+	var handles []*Handle
+	for _, r := range runners {
+		handles = append(handles, r.Handles()...)
+	}
+
+	return handles
+}
+
+// unregister removes the given runner from the health server, and closes the server once no runners are left.
+func (s *healthServer) unregister(r *Runner) {
+	healthServersMtx.Lock()
+	defer healthServersMtx.Unlock()
+
+	s.runners = slices.DeleteFunc(s.runners, func(runner *Runner) bool {
+		return runner == r
+	})
+	if len(s.runners) > 0 {
+		return
+	}
+
+	if healthServers[s.addr] == s {
+		delete(healthServers, s.addr)
+	}
+	_ = s.server.Close()
+}
@@ -28,3 +28,8 @@ const (
 	// between normal shutdowns and error-induced terminations.
 	HealthStatusShutdown
 )
+
+// MarshalText encodes the HealthStatus as its name, e.g. "Healthy".
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+const (
+	// DefaultJobMaxAttempts is the maximum number of attempts of a Job if none is configured.
+	DefaultJobMaxAttempts = 3
+	// DefaultJobTimeoutExitCode is the exit code of a Job that exceeded its maximum runtime if none is configured.
+	// It matches the exit code of the timeout command.
+	DefaultJobTimeoutExitCode = 124
+)
+
+// JobOption is a function that modifies JobOptions.
+// It is used to configure services created using Job.
+type JobOption = func(*JobOptions)
+
+// JobOptions holds options for jobs.
+type JobOptions struct {
+	// Namespace is the namespace of the job.
+	Namespace string
+	// Version is the version of the job.
+	Version string
+	// MaxAttempts is the maximum number of times the job function is run, including the first attempt.
+	// Values smaller than 1 are treated as 1.
+	// Defaults to DefaultJobMaxAttempts.
+	MaxAttempts int
+	// Backoff configures the delay between attempts.
+	// Defaults to DefaultBackoff.
+	Backoff Backoff
+	// MaxRuntime is the maximum duration of the job, including all attempts and the delays between them.
+	// The context passed to the job function is canceled once it is exceeded. Zero means unlimited.
+	MaxRuntime time.Duration
+	// RetryIf decides whether a failed attempt is retried.
+	// Defaults to IsRetryable, i.e. only errors marked using Retryable are retried.
+	RetryIf func(error) bool
+	// FailureExitCode is the exit code of a failed job.
+	// Defaults to fail.DefaultExitCode.
+	FailureExitCode int
+	// TimeoutExitCode is the exit code of a job that exceeded MaxRuntime.
+	// Defaults to DefaultJobTimeoutExitCode.
+	TimeoutExitCode int
+}
+
+// JobHealth contains details about the attempts of a job.
+// It is reported as the Details of the Health of services created using Job.
+type JobHealth struct {
+	// Attempts is the number of attempts that have been started.
+	Attempts int `json:"attempts"`
+	// LastError is the error returned by the last finished attempt, if any.
+	LastError error `json:"lastError,omitempty"`
+	// Started is the time the job was started, or zero if it has not been started yet.
+	Started time.Time `json:"started"`
+	// Finished is the time the job finished, or zero if it has not finished yet.
+	Finished time.Time `json:"finished"`
+	// Succeeded reports whether the job has finished successfully.
+	Succeeded bool `json:"succeeded"`
+}
+
+// MarshalJSON encodes the JobHealth as a JSON object, where the last error is encoded as its message.
+func (h JobHealth) MarshalJSON() ([]byte, error) {
+	type jobHealth JobHealth
+
+	j := struct {
+		jobHealth
+		LastError string `json:"lastError,omitempty"`
+	}{jobHealth: jobHealth(h)}
+	if h.LastError != nil {
+		j.LastError = h.LastError.Error()
+	}
+
+	return json.Marshal(j)
+}
+
+// JobError is returned by a Job that has failed, either because an attempt failed and was not retried,
+// because all attempts failed, or because the job exceeded its maximum runtime.
+// It carries the configured exit code, so that RunAndExit exits with it.
+type JobError struct {
+	// Job is the name of the failed job.
+	Job string
+	// Attempts is the number of attempts that have been made.
+	Attempts int
+	// TimedOut reports whether the job exceeded its maximum runtime.
+	TimedOut bool
+	// ExitCode is the exit code of the failed job.
+	ExitCode int
+	// Err is the error returned by the last attempt.
+	Err error
+}
+
+// Error returns a human-readable description of the failed job.
+func (e *JobError) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("job %s exceeded its maximum runtime after %d attempt(s)", e.Job, e.Attempts)
+	}
+
+	return fmt.Sprintf("job %s failed after %d attempt(s)", e.Job, e.Attempts)
+}
+
+// Unwrap returns the error returned by the last attempt.
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorExitCode returns the exit code of the failed job, see fail.ErrorExitCode.
+func (e *JobError) ErrorExitCode() int {
+	return e.ExitCode
+}
+
+// RetryableError marks an error as retryable, see Retryable.
+type RetryableError struct {
+	// Err is the retryable error.
+	Err error
+}
+
+// Error returns the message of the retryable error.
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the retryable error.
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCategory returns ExitCategoryTemporary, as a retryable error indicates a temporary failure.
+func (e *RetryableError) ExitCategory() ExitCategory {
+	return ExitCategoryTemporary
+}
+
+// Retryable marks the given error as retryable, causing a Job to retry the failed attempt.
+// Returns nil if err is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &RetryableError{Err: err}
+}
+
+// IsRetryable reports whether the given error, or any of its causes, has been marked using Retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+
+	// fail errors do not support errors.As, so their causes are inspected directly
+	for _, cause := range fail.Causes(err) {
+		if IsRetryable(cause) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultJobOptions returns a JobOptions struct with default values.
+func DefaultJobOptions() *JobOptions {
+	return &JobOptions{
+		MaxAttempts:     DefaultJobMaxAttempts,
+		Backoff:         DefaultBackoff(),
+		RetryIf:         IsRetryable,
+		FailureExitCode: fail.DefaultExitCode,
+		TimeoutExitCode: DefaultJobTimeoutExitCode,
+	}
+}
+
+// WithJobNamespace returns a JobOption that sets the namespace of the job.
+func WithJobNamespace(namespace string) JobOption {
+	return func(o *JobOptions) {
+		o.Namespace = namespace
+	}
+}
+
+// WithJobVersion returns a JobOption that sets the version of the job.
+func WithJobVersion(version string) JobOption {
+	return func(o *JobOptions) {
+		o.Version = version
+	}
+}
+
+// WithMaxAttempts returns a JobOption that sets the maximum number of attempts.
+func WithMaxAttempts(attempts int) JobOption {
+	return func(o *JobOptions) {
+		o.MaxAttempts = attempts
+	}
+}
+
+// WithJobBackoff returns a JobOption that sets the Backoff between attempts.
+func WithJobBackoff(backoff Backoff) JobOption {
+	return func(o *JobOptions) {
+		o.Backoff = backoff
+	}
+}
+
+// WithMaxRuntime returns a JobOption that sets the maximum runtime of the job.
+func WithMaxRuntime(runtime time.Duration) JobOption {
+	return func(o *JobOptions) {
+		o.MaxRuntime = runtime
+	}
+}
+
+// WithRetryIf returns a JobOption that sets the function deciding whether a failed attempt is retried.
+// Nil is ignored.
+func WithRetryIf(fn func(error) bool) JobOption {
+	return func(o *JobOptions) {
+		if fn != nil {
+			o.RetryIf = fn
+		}
+	}
+}
+
+// WithJobExitCodes returns a JobOption that sets the exit codes of a failed job and of a job that exceeded
+// its maximum runtime. Non-positive exit codes are ignored.
+func WithJobExitCodes(failure, timeout int) JobOption {
+	return func(o *JobOptions) {
+		if failure > 0 {
+			o.FailureExitCode = failure
+		}
+		if timeout > 0 {
+			o.TimeoutExitCode = timeout
+		}
+	}
+}
+
+// Job returns a Service that runs fn to completion once, instead of running until it is shut down.
+//
+// A failed attempt is retried with backoff if it is retryable (see Retryable and WithRetryIf),
+// until the maximum number of attempts is reached. The number of the current attempt is available
+// using Context.Attempt. If all attempts fail, or the job exceeds its maximum runtime, the job fails
+// with a *JobError carrying a distinct exit code, which is used by RunAndExit.
+//
+// Jobs are never restarted by a supervisor. The Health of a job reports its progress and outcome as JobHealth.
+func Job(name string, fn func(*Context) error, opts ...JobOption) Service {
+	o := DefaultJobOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	j := &job{
+		name: name,
+		opts: o,
+		fn:   fn,
+	}
+
+	return &jobService{
+		simpleService: &simpleService{
+			name:      name,
+			namespace: o.Namespace,
+			version:   o.Version,
+			fn:        j.run,
+		},
+		job: j,
+	}
+}
+
+// jobService is the Service returned by Job.
+type jobService struct {
+	*simpleService
+	job *job
+}
+
+// Health returns the current health status of the job, with JobHealth as details.
+func (s *jobService) Health() Health {
+	health := s.simpleService.Health()
+	status := s.job.status()
+
+	health.Details = status
+	if status.Succeeded {
+		health.Reason = "job completed"
+	} else if status.LastError != nil {
+		health.Reason = fmt.Sprintf("attempt %d failed", status.Attempts)
+		if health.Error == nil {
+			health.Error = status.LastError
+		}
+	}
+
+	return health
+}
+
+// RestartPolicy returns RestartNever, as a job must not be run again once it has completed.
+func (s *jobService) RestartPolicy() RestartPolicy {
+	return RestartNever
+}
+
+// job runs a function to completion with retries.
+type job struct {
+	name string
+	opts *JobOptions
+	fn   func(*Context) error
+
+	mtx    sync.RWMutex
+	health JobHealth
+}
+
+// run runs the job function until it succeeds, fails with a non-retryable error, runs out of attempts,
+// or the job exceeds its maximum runtime.
+func (j *job) run(ctx *Context) error {
+	runCtx, cancel := context.WithCancel(ctx.Context)
+	if j.opts.MaxRuntime > 0 {
+		runCtx, cancel = context.WithTimeout(ctx.Context, j.opts.MaxRuntime)
+	}
+	defer cancel()
+
+	j.start()
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx := ctx.withContext(runCtx)
+		attemptCtx.attempt = attempt
+
+		j.setAttempt(attempt)
+		ctx.Debug("Running job", "attempt", attempt)
+
+		err := j.runAttempt(attemptCtx)
+		j.finishAttempt(err)
+		if err == nil {
+			ctx.Info("Job completed", "attempts", attempt)
+			return nil
+		}
+
+		if runCtx.Err() != nil || attempt >= j.opts.MaxAttempts || !j.opts.RetryIf(err) {
+			return j.failed(ctx, runCtx, attempt, err)
+		}
+
+		delay := j.opts.Backoff.Delay(attempt - 1)
+		ctx.Warn("Job attempt failed, retrying", "attempt", attempt, "delay", delay.String(), "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-runCtx.Done():
+			return j.failed(ctx, runCtx, attempt, err)
+		}
+	}
+}
+
+// runAttempt runs a single attempt of the job function, recovering from panics as a PanicError.
+func (j *job) runAttempt(ctx *Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(ctx.identity(), PhaseRunning, r)
+		}
+	}()
+
+	return j.fn(ctx)
+}
+
+// failed returns the JobError for the job failing after the given attempt with the given error.
+func (j *job) failed(ctx *Context, runCtx context.Context, attempts int, err error) error {
+	// the maximum runtime was exceeded if the run context is done, but the service context is not
+	timedOut := runCtx.Err() != nil && ctx.Err() == nil
+
+	jobErr := &JobError{
+		Job:      j.name,
+		Attempts: attempts,
+		TimedOut: timedOut,
+		ExitCode: j.opts.FailureExitCode,
+		Err:      err,
+	}
+	if timedOut {
+		jobErr.ExitCode = j.opts.TimeoutExitCode
+	}
+
+	j.mtx.Lock()
+	j.health.Finished = time.Now()
+	j.mtx.Unlock()
+
+	ctx.Error("Job failed", "attempts", attempts, "timedOut", timedOut, "error", err)
+
+	return jobErr
+}
+
+// start resets the recorded state and records the start of the job.
+func (j *job) start() {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.health = JobHealth{Started: time.Now()}
+}
+
+// setAttempt records the start of the given attempt.
+func (j *job) setAttempt(attempt int) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.health.Attempts = attempt
+}
+
+// finishAttempt records the outcome of the current attempt.
+func (j *job) finishAttempt(err error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	j.health.LastError = err
+	if err == nil {
+		j.health.Succeeded = true
+		j.health.Finished = time.Now()
+	}
+}
+
+// status returns the recorded state of the job.
+func (j *job) status() JobHealth {
+	j.mtx.RLock()
+	defer j.mtx.RUnlock()
+
+	return j.health
+}
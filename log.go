@@ -102,9 +102,37 @@ func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
 }
 
 func LoggerFromEnv(prefix string) *slog.Logger {
-	level := LogLevelFromEnv(prefix)
-	format := LogFormatFromEnv(prefix)
+	return newLogger(LogLevelFromEnv(prefix), LogFormatFromEnv(prefix))
+}
+
+// serviceLogger returns the logger of a service, taken from the RunOptions of the context if set.
+// Otherwise, a logger is created using the log level and format of the RunOptions or the context,
+// falling back to the environment with the given prefix.
+func serviceLogger(ctx context.Context, prefix string) *slog.Logger {
+	opts := RunOptionsFromContext(ctx)
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+
+	level := opts.LogLevel
+	if level == nil {
+		if l, ok := ctx.Value(logLevelKey{}).(slog.Leveler); ok {
+			level = l
+		} else {
+			level = LogLevelFromEnv(prefix)
+		}
+	}
+
+	format, ok := ctx.Value(logFormatKey{}).(LogFormat)
+	if !ok {
+		format = LogFormatFromEnv(prefix)
+	}
+
+	return newLogger(level, format)
+}
 
+// newLogger creates a logger writing to stdout with the given level and format.
+func newLogger(level slog.Leveler, format LogFormat) *slog.Logger {
 	var handler slog.Handler
 	switch format {
 	case LogFormatText:
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runOptionsKey is the context key type for storing the RunOptions.
+type runOptionsKey struct{}
+
+// RunOption is a function that modifies RunOptions.
+// It is used to configure how services are set up when they are run.
+type RunOption = func(*RunOptions)
+
+// RunOptions holds options for setting up services, which take precedence over the environment.
+// They allow tests and embedders to inject their own observability stack, see WithRunOptions.
+type RunOptions struct {
+	// Logger is the logger services log to, to which the identity of each service is added.
+	// If nil, a logger is created from the environment, see LoggerFromEnv.
+	Logger *slog.Logger
+	// LogLevel is the level of the logger created from the environment.
+	// If nil, it is taken from the context (see WithLogLevel) or the environment (see LogLevelFromEnv).
+	// It is ignored if Logger is set.
+	LogLevel slog.Leveler
+	// TracerProvider is the TracerProvider of services. It is used regardless of whether OpenTelemetry
+	// is enabled, and is not shut down when services exit.
+	// If nil, a TracerProvider is created from the environment, see TracerProviderFromEnv.
+	TracerProvider trace.TracerProvider
+	// MeterProvider is the MeterProvider of services. It is used regardless of whether OpenTelemetry
+	// is enabled, and is not shut down when services exit.
+	// If nil, a MeterProvider is created from the environment, see MeterProviderFromEnv.
+	MeterProvider metric.MeterProvider
+	// Propagator is the TextMapPropagator of services.
+	// If nil, the W3C trace context and baggage propagators are used.
+	Propagator propagation.TextMapPropagator
+	// Resource is merged into the OpenTelemetry resource of the providers created from the environment,
+	// where the attributes identifying the service take precedence.
+	Resource *resource.Resource
+	// EnvPrefix is the prefix of the environment variables used to set up services, e.g. {PREFIX}_LOG_LEVEL,
+	// and runners, e.g. {PREFIX}_SHUTDOWN_GRACE_PERIOD and {PREFIX}_HEALTH_ADDR.
+	// If empty, the name of each service is used for services, and the default prefix for runners.
+	EnvPrefix string
+}
+
+// DefaultRunOptions returns a RunOptions struct with default values.
+// By default, services are set up entirely from the environment.
+func DefaultRunOptions() *RunOptions {
+	return &RunOptions{}
+}
+
+// WithRunOptions returns a new context with the given RunOptions applied on top of the RunOptions of ctx.
+// All services run with the returned context are set up accordingly.
+func WithRunOptions(ctx context.Context, opts ...RunOption) context.Context {
+	o := RunOptionsFromContext(ctx)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return context.WithValue(ctx, runOptionsKey{}, o)
+}
+
+// RunOptionsFromContext retrieves a copy of the RunOptions from the context.
+// If no RunOptions are set in the context, DefaultRunOptions is returned.
+func RunOptionsFromContext(ctx context.Context) *RunOptions {
+	if o, ok := ctx.Value(runOptionsKey{}).(*RunOptions); ok {
+		c := *o
+		return &c
+	}
+
+	return DefaultRunOptions()
+}
+
+// RunWithOptions runs the given service like Run, set up using the given options.
+func RunWithOptions(ctx context.Context, svc Service, opts ...RunOption) *Handle {
+	return Run(WithRunOptions(ctx, opts...), svc)
+}
+
+// RunGroupWithOptions runs the given services as a group like RunGroup, set up using the given options.
+func RunGroupWithOptions(ctx context.Context, svcs []Service, opts ...RunOption) []*Handle {
+	return RunGroup(WithRunOptions(ctx, opts...), svcs...)
+}
+
+// WithRunLogger returns a RunOption that sets the logger services log to.
+func WithRunLogger(logger *slog.Logger) RunOption {
+	return func(o *RunOptions) {
+		o.Logger = logger
+	}
+}
+
+// WithRunLogLevel returns a RunOption that sets the level of the logger created from the environment.
+func WithRunLogLevel(level slog.Leveler) RunOption {
+	return func(o *RunOptions) {
+		o.LogLevel = level
+	}
+}
+
+// WithRunTracerProvider returns a RunOption that sets the TracerProvider of services.
+func WithRunTracerProvider(provider trace.TracerProvider) RunOption {
+	return func(o *RunOptions) {
+		o.TracerProvider = provider
+	}
+}
+
+// WithRunMeterProvider returns a RunOption that sets the MeterProvider of services.
+func WithRunMeterProvider(provider metric.MeterProvider) RunOption {
+	return func(o *RunOptions) {
+		o.MeterProvider = provider
+	}
+}
+
+// WithPropagator returns a RunOption that sets the TextMapPropagator of services.
+func WithPropagator(propagator propagation.TextMapPropagator) RunOption {
+	return func(o *RunOptions) {
+		o.Propagator = propagator
+	}
+}
+
+// WithResource returns a RunOption that sets the OpenTelemetry resource merged into the resource of services.
+func WithResource(res *resource.Resource) RunOption {
+	return func(o *RunOptions) {
+		o.Resource = res
+	}
+}
+
+// WithEnvPrefix returns a RunOption that sets the prefix of the environment variables used to set up services.
+func WithEnvPrefix(prefix string) RunOption {
+	return func(o *RunOptions) {
+		o.EnvPrefix = prefix
+	}
+}
+
+// runnerEnvPrefix returns the prefix of the environment variables used to set up a runner, e.g. its signal handling,
+// which is the EnvPrefix of the RunOptions of the context, or empty for the default prefix.
+func runnerEnvPrefix(ctx context.Context) string {
+	return RunOptionsFromContext(ctx).EnvPrefix
+}
+
+// envPrefix returns the prefix of the environment variables used to set up the given service.
+func envPrefix(ctx context.Context, svc Service) string {
+	if o, ok := ctx.Value(runOptionsKey{}).(*RunOptions); ok && o.EnvPrefix != "" {
+		return o.EnvPrefix
+	}
+
+	return svc.Name()
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// Pausable can optionally be implemented by a Service to support temporarily suspending its work
+// without going through a full Shutdown, Initialize and Run cycle.
+//
+// Pause and Resume are called while the service is running, using Handle.Pause and Handle.Resume.
+// While pausing, the service is in PhasePausing, and in PhasePaused once Pause has returned successfully.
+// While resuming, the service is in PhaseResuming, and in PhaseRunning again once Resume has returned successfully.
+// A paused service is not ready (see Handle.Ready), is not drained when it is shut down, and cannot be reloaded.
+type Pausable interface {
+	// Pause suspends the work of the service, e.g. by no longer consuming messages.
+	// It is called concurrently to Run, but never concurrently to Resume or Reloadable.Reload.
+	// The provided context is canceled if pausing is aborted.
+	Pause(*Context) error
+	// Resume resumes the work of the service after it has been paused.
+	// It is called concurrently to Run, but never concurrently to Pause or Reloadable.Reload.
+	// The provided context is canceled if resuming is aborted.
+	Resume(*Context) error
+}
+
+// Pause pauses the service instance, using the provided context for cancellation.
+// The service must implement Pausable and be running, otherwise ErrServiceNotPausable or ErrServiceNotRunning
+// is returned. Pausing an already paused service does nothing.
+// If Pause returns an error, the service keeps running and the error is returned.
+func (h *Handle) Pause(ctx context.Context) error {
+	p, ok := h.svc.(Pausable)
+	if !ok {
+		return ErrServiceNotPausable
+	}
+
+	h.controlMtx.Lock()
+	defer h.controlMtx.Unlock()
+
+	lc := h.getLifecycle()
+	if lc == nil || lc.isRunDone() {
+		return ErrServiceNotRunning
+	}
+	if h.Phase() == PhasePaused {
+		return nil
+	}
+	if !h.compareAndSetPhase(PhaseRunning, PhasePausing, nil) {
+		return ErrServiceNotRunning
+	}
+
+	lc.ctx.Info("Pausing")
+	started := time.Now()
+
+	err := h.callService(ctx, lc, PhasePausing, p.Pause)
+	if err != nil {
+		lc.ctx.Error("Pausing failed", "error", err, "duration", time.Since(started).String())
+
+		// the service may have stopped running while pausing
+		h.compareAndSetPhase(PhasePausing, PhaseRunning, err)
+		return err
+	}
+
+	lc.ctx.Info("Paused", "duration", time.Since(started).String())
+	h.compareAndSetPhase(PhasePausing, PhasePaused, nil)
+
+	return nil
+}
+
+// Resume resumes the paused service instance, using the provided context for cancellation.
+// The service must implement Pausable and be paused, otherwise ErrServiceNotPausable or ErrServiceNotPaused
+// is returned. If Resume returns an error, the service stays paused and the error is returned.
+func (h *Handle) Resume(ctx context.Context) error {
+	p, ok := h.svc.(Pausable)
+	if !ok {
+		return ErrServiceNotPausable
+	}
+
+	h.controlMtx.Lock()
+	defer h.controlMtx.Unlock()
+
+	lc := h.getLifecycle()
+	if lc == nil || lc.isRunDone() || !h.compareAndSetPhase(PhasePaused, PhaseResuming, nil) {
+		return ErrServiceNotPaused
+	}
+
+	lc.ctx.Info("Resuming")
+	started := time.Now()
+
+	err := h.callService(ctx, lc, PhaseResuming, p.Resume)
+	if err != nil {
+		lc.ctx.Error("Resuming failed", "error", err, "duration", time.Since(started).String())
+
+		// the service may have stopped running while resuming
+		h.compareAndSetPhase(PhaseResuming, PhasePaused, err)
+		return err
+	}
+
+	lc.ctx.Info("Resumed", "duration", time.Since(started).String())
+	h.compareAndSetPhase(PhaseResuming, PhaseRunning, nil)
+
+	return nil
+}
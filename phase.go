@@ -18,4 +18,16 @@ const (
 	PhaseFinished
 	// PhaseFailed indicates the service has failed.
 	PhaseFailed
+	// PhaseRestarting indicates the service has returned and is waiting to be restarted by its supervisor.
+	PhaseRestarting
+	// PhaseReloading indicates the service is running and reloading its configuration, see Reloadable.
+	PhaseReloading
+	// PhaseDraining indicates the service is running, but has been requested to shut down and no longer accepts new work, see Drainer.
+	PhaseDraining
+	// PhasePausing indicates the service is running and being paused, see Pausable.
+	PhasePausing
+	// PhasePaused indicates the service has been paused and does not process any work until it is resumed, see Pausable.
+	PhasePaused
+	// PhaseResuming indicates the service is paused and being resumed, see Pausable.
+	PhaseResuming
 )
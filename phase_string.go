@@ -14,11 +14,17 @@ func _() {
 	_ = x[PhaseShuttingDown-3]
 	_ = x[PhaseFinished-4]
 	_ = x[PhaseFailed-5]
+	_ = x[PhaseRestarting-6]
+	_ = x[PhaseReloading-7]
+	_ = x[PhaseDraining-8]
+	_ = x[PhasePausing-9]
+	_ = x[PhasePaused-10]
+	_ = x[PhaseResuming-11]
 }
 
-const _Phase_name = "WaitingInitializingRunningShuttingDownFinishedFailed"
+const _Phase_name = "WaitingInitializingRunningShuttingDownFinishedFailedRestartingReloadingDrainingPausingPausedResuming"
 
-var _Phase_index = [...]uint8{0, 7, 19, 26, 38, 46, 52}
+var _Phase_index = [...]uint8{0, 7, 19, 26, 38, 46, 52, 62, 71, 79, 86, 92, 100}
 
 func (i Phase) String() string {
 	if i < 0 || i >= Phase(len(_Phase_index)-1) {
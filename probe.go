@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+// maxProbeResponse is the maximum number of bytes of a response that are read and matched by a probe.
+const maxProbeResponse = 1 << 20
+
+// maxProbeOutput is the maximum number of bytes of the output of a command that are reported by ExecProbe.
+const maxProbeOutput = 256
+
+// ProbeOption is a function that modifies ProbeOptions.
+// It is used to configure the probes returned by TCPProbe, HTTPProbe, DNSProbe, ExecProbe and FileProbe.
+type ProbeOption = func(*ProbeOptions)
+
+// ProbeOptions holds options for a probe. Options that do not apply to a probe are ignored by it.
+type ProbeOptions struct {
+	// Timeout is the maximum duration of a single execution of the probe.
+	Timeout time.Duration
+	// Expect matches the response of the probe: the body of an HTTP response, the combined output of a command,
+	// the content of a file, or the resolved addresses of a host, one per line. If nil, any response passes.
+	Expect func(response []byte) bool
+	// ExpectCode matches the status code of an HTTP response or the exit code of a command.
+	// If nil, status codes from 200 to 399 and the exit code 0 pass.
+	ExpectCode func(code int) bool
+	// MaxAge is the maximum time since a file has been modified. If zero, the age of a file is not checked.
+	MaxAge time.Duration
+	// Method is the method of HTTP requests. If empty, GET is used.
+	Method string
+	// Header contains the headers of HTTP requests.
+	Header http.Header
+	// Client is the client sending HTTP requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// Resolver is the resolver used to look up hosts. If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+}
+
+// DefaultProbeOptions returns a ProbeOptions struct with default values.
+// By default, probes time out after 5 seconds.
+func DefaultProbeOptions() *ProbeOptions {
+	return &ProbeOptions{
+		Timeout: 5 * time.Second,
+		Header:  http.Header{},
+	}
+}
+
+// WithProbeTimeout returns a ProbeOption that sets the maximum duration of a single execution of the probe.
+// Non-positive timeouts are ignored.
+func WithProbeTimeout(timeout time.Duration) ProbeOption {
+	return func(o *ProbeOptions) {
+		if timeout > 0 {
+			o.Timeout = timeout
+		}
+	}
+}
+
+// WithExpect returns a ProbeOption that sets the matcher of the response of the probe, see ProbeOptions.Expect.
+func WithExpect(match func(response []byte) bool) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.Expect = match
+	}
+}
+
+// WithExpectContains returns a ProbeOption that requires the response of the probe to contain s.
+func WithExpectContains(s string) ProbeOption {
+	return WithExpect(func(response []byte) bool {
+		return bytes.Contains(response, []byte(s))
+	})
+}
+
+// WithExpectRegexp returns a ProbeOption that requires the response of the probe to match re.
+func WithExpectRegexp(re *regexp.Regexp) ProbeOption {
+	return WithExpect(re.Match)
+}
+
+// WithExpectCode returns a ProbeOption that requires the status code of an HTTP response or the exit code of
+// a command to be one of the given codes.
+func WithExpectCode(codes ...int) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.ExpectCode = func(code int) bool {
+			return slices.Contains(codes, code)
+		}
+	}
+}
+
+// WithMaxAge returns a ProbeOption that sets the maximum time since a file has been modified.
+func WithMaxAge(maxAge time.Duration) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.MaxAge = maxAge
+	}
+}
+
+// WithProbeMethod returns a ProbeOption that sets the method of HTTP requests.
+func WithProbeMethod(method string) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.Method = method
+	}
+}
+
+// WithProbeHeader returns a ProbeOption that adds a header to HTTP requests.
+func WithProbeHeader(key string, value string) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.Header.Add(key, value)
+	}
+}
+
+// WithProbeClient returns a ProbeOption that sets the client sending HTTP requests.
+func WithProbeClient(client *http.Client) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.Client = client
+	}
+}
+
+// WithProbeResolver returns a ProbeOption that sets the resolver used to look up hosts.
+func WithProbeResolver(resolver *net.Resolver) ProbeOption {
+	return func(o *ProbeOptions) {
+		o.Resolver = resolver
+	}
+}
+
+// TCPProbe returns a CheckFunc that passes if a TCP connection to the given address can be established.
+// The address and the latency are reported in the details.
+//
+// Example usage:
+//
+//	ctx.RegisterCheck("redis", service.TCPProbe("redis:6379"))
+func TCPProbe(addr string, opts ...ProbeOption) CheckFunc {
+	return newProbe(opts, func(ctx context.Context, o *ProbeOptions, details map[string]any) error {
+		details["addr"] = addr
+
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fail.New().
+				Attribute("addr", addr).
+				Cause(err).
+				Msgf("failed to connect to %s", addr)
+		}
+
+		return conn.Close()
+	})
+}
+
+// HTTPProbe returns a CheckFunc that passes if a request to the given URL returns an expected status code,
+// by default from 200 to 399, and a response body matching the expectation, if any.
+// The URL, the status code and the latency are reported in the details.
+//
+// Example usage:
+//
+//	ctx.RegisterCheck("payments", service.HTTPProbe("http://payments/healthz", service.WithExpectCode(200)))
+func HTTPProbe(url string, opts ...ProbeOption) CheckFunc {
+	return newProbe(opts, func(ctx context.Context, o *ProbeOptions, details map[string]any) error {
+		details["url"] = url
+
+		method := o.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return fail.New().
+				Attribute("url", url).
+				Cause(err).
+				Msgf("invalid request to %s", url)
+		}
+		req.Header = o.Header.Clone()
+
+		client := o.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fail.New().
+				Attribute("url", url).
+				Cause(err).
+				Msgf("request to %s failed", url)
+		}
+		defer resp.Body.Close()
+
+		details["statusCode"] = resp.StatusCode
+
+		expectCode := o.ExpectCode
+		if expectCode == nil {
+			expectCode = func(code int) bool {
+				return code >= 200 && code < 400
+			}
+		}
+		if !expectCode(resp.StatusCode) {
+			return fail.New().
+				Attribute("url", url).
+				Attribute("status_code", resp.StatusCode).
+				Msgf("request to %s returned unexpected status %d", url, resp.StatusCode)
+		}
+
+		if o.Expect != nil {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeResponse))
+			if err != nil {
+				return fail.New().
+					Attribute("url", url).
+					Cause(err).
+					Msgf("failed to read response of %s", url)
+			}
+
+			if !o.Expect(body) {
+				return fail.New().
+					Attribute("url", url).
+					Msgf("request to %s returned unexpected response", url)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DNSProbe returns a CheckFunc that passes if the given host can be resolved to at least one address,
+// and the addresses match the expectation, if any. The host, the resolved addresses and the latency are
+// reported in the details.
+func DNSProbe(host string, opts ...ProbeOption) CheckFunc {
+	return newProbe(opts, func(ctx context.Context, o *ProbeOptions, details map[string]any) error {
+		details["host"] = host
+
+		resolver := o.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+
+		addrs, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return fail.New().
+				Attribute("host", host).
+				Cause(err).
+				Msgf("failed to resolve %s", host)
+		}
+
+		details["addrs"] = addrs
+
+		if o.Expect != nil && !o.Expect([]byte(strings.Join(addrs, "\n"))) {
+			return fail.New().
+				Attribute("host", host).
+				Attribute("addrs", addrs).
+				Msgf("%s resolved to unexpected addresses", host)
+		}
+
+		return nil
+	})
+}
+
+// ExecProbe returns a CheckFunc that passes if the given command exits with an expected exit code, by default 0,
+// and its combined output matches the expectation, if any. The command is killed once the probe times out.
+// The command, the exit code, the beginning of the output and the latency are reported in the details.
+func ExecProbe(name string, args []string, opts ...ProbeOption) CheckFunc {
+	return newProbe(opts, func(ctx context.Context, o *ProbeOptions, details map[string]any) error {
+		command := strings.Join(append([]string{name}, args...), " ")
+		details["command"] = command
+
+		output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+
+		code := 0
+		if err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || ctx.Err() != nil {
+				return fail.New().
+					Attribute("command", command).
+					Cause(err).
+					Msgf("failed to run %s", name)
+			}
+
+			code = exitErr.ExitCode()
+		}
+
+		details["exitCode"] = code
+		details["output"] = truncateOutput(output)
+
+		expectCode := o.ExpectCode
+		if expectCode == nil {
+			expectCode = func(code int) bool {
+				return code == 0
+			}
+		}
+		if !expectCode(code) {
+			return fail.New().
+				Attribute("command", command).
+				Attribute("exit_code", code).
+				Msgf("%s exited with unexpected code %d", name, code)
+		}
+
+		if o.Expect != nil && !o.Expect(output) {
+			return fail.New().
+				Attribute("command", command).
+				Msgf("%s returned unexpected output", name)
+		}
+
+		return nil
+	})
+}
+
+// FileProbe returns a CheckFunc that passes if the given file exists, has been modified within the maximum age,
+// if any, and its content matches the expectation, if any. The path, the age of the file and the latency are
+// reported in the details.
+//
+// Example usage:
+//
+//	ctx.RegisterCheck("heartbeat", service.FileProbe("/tmp/heartbeat", service.WithMaxAge(time.Minute)))
+func FileProbe(path string, opts ...ProbeOption) CheckFunc {
+	return newProbe(opts, func(_ context.Context, o *ProbeOptions, details map[string]any) error {
+		details["path"] = path
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return fail.New().
+				Attribute("path", path).
+				Cause(err).
+				Msgf("failed to stat %s", path)
+		}
+
+		age := time.Since(info.ModTime())
+		details["age"] = age.String()
+
+		if o.MaxAge > 0 && age > o.MaxAge {
+			return fail.New().
+				Attribute("path", path).
+				Attribute("age", age.String()).
+				Msgf("%s has not been modified for %s", path, age.Round(time.Second))
+		}
+
+		if o.Expect != nil {
+			f, err := os.Open(path)
+			if err != nil {
+				return fail.New().
+					Attribute("path", path).
+					Cause(err).
+					Msgf("failed to open %s", path)
+			}
+			defer f.Close()
+
+			content, err := io.ReadAll(io.LimitReader(f, maxProbeResponse))
+			if err != nil {
+				return fail.New().
+					Attribute("path", path).
+					Cause(err).
+					Msgf("failed to read %s", path)
+			}
+
+			if !o.Expect(content) {
+				return fail.New().
+					Attribute("path", path).
+					Msgf("%s has unexpected content", path)
+			}
+		}
+
+		return nil
+	})
+}
+
+// newProbe returns a CheckFunc executing fn bounded by the timeout of the probe, which reports its
+// details and latency in the details of the returned Health.
+func newProbe(opts []ProbeOption, fn func(ctx context.Context, o *ProbeOptions, details map[string]any) error) CheckFunc {
+	o := DefaultProbeOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(ctx context.Context) Health {
+		ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+
+		details := map[string]any{}
+		started := time.Now()
+		err := fn(ctx, o, details)
+		details["latency"] = time.Since(started).String()
+
+		if err != nil {
+			return Health{
+				Status:  HealthStatusError,
+				Reason:  fail.Message(err),
+				Details: details,
+				Error:   err,
+			}
+		}
+
+		return Health{
+			Status:  HealthStatusHealthy,
+			Details: details,
+		}
+	}
+}
+
+// truncateOutput returns the beginning of the given output of a command as a string.
+func truncateOutput(output []byte) string {
+	output = bytes.TrimSpace(output)
+	if len(output) > maxProbeOutput {
+		return string(output[:maxProbeOutput]) + "..."
+	}
+
+	return string(output)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+// reloadSignalsKey is the context key type for storing the signals that trigger a reload.
+type reloadSignalsKey struct{}
+
+// Reloadable can optionally be implemented by a Service to support reloading its configuration
+// without going through a full Initialize, Run and Shutdown cycle.
+//
+// Reload is called while the service is running, either explicitly using Handle.Reload or Runner.Reload,
+// or when one of the reload signals is received (see WithReloadSignals).
+// While reloading, the service is in PhaseReloading. If Reload returns an error, the service keeps running
+// and is reported as degraded by Handle.Health until the next successful reload.
+type Reloadable interface {
+	// Reload reloads the configuration of the service, e.g. by calling ReadConfig again.
+	// It is called concurrently to Run, but never concurrently to itself.
+	// The provided context is canceled if the reload is aborted.
+	Reload(*Context) error
+}
+
+// reloadResult is the outcome of the last reload of a service.
+type reloadResult struct {
+	time time.Time
+	err  error
+}
+
+// DefaultReloadSignals returns the signals that trigger a reload if none are specified.
+// This is SIGHUP.
+func DefaultReloadSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
+}
+
+// WithReloadSignals returns a new context that enables reloading services run with it
+// when one of the given signals is received. If no signals are provided, DefaultReloadSignals is used.
+//
+// Reloading on DefaultReloadSignals is also enabled whenever signal handling is enabled using WithShutdownSignals,
+// which is always the case for RunAndExit, RunParallelAndExit and RunGroupAndExit.
+func WithReloadSignals(ctx context.Context, signals ...os.Signal) context.Context {
+	if len(signals) == 0 {
+		signals = DefaultReloadSignals()
+	}
+
+	return context.WithValue(ctx, reloadSignalsKey{}, signals)
+}
+
+// ReloadSignals retrieves the signals that trigger a reload from the context.
+// If no reload signals are set, but signal handling is enabled using WithShutdownSignals, DefaultReloadSignals is returned.
+// The boolean reports whether reloading on signals is enabled for the context.
+func ReloadSignals(ctx context.Context) ([]os.Signal, bool) {
+	if signals, ok := ctx.Value(reloadSignalsKey{}).([]os.Signal); ok {
+		return signals, true
+	}
+
+	if _, ok := ShutdownSignals(ctx); ok {
+		return DefaultReloadSignals(), true
+	}
+
+	return nil, false
+}
+
+// Reload reloads the configuration of the service instance, using the provided context for cancellation.
+// The service must implement Reloadable and be running, otherwise ErrServiceNotReloadable or ErrServiceNotRunning
+// is returned. Concurrent reloads of the same service are serialized.
+// Returns the error returned by the service, if any.
+func (h *Handle) Reload(ctx context.Context) error {
+	r, ok := h.svc.(Reloadable)
+	if !ok {
+		return ErrServiceNotReloadable
+	}
+
+	h.controlMtx.Lock()
+	defer h.controlMtx.Unlock()
+
+	lc := h.getLifecycle()
+	if lc == nil || lc.isRunDone() || !h.compareAndSetPhase(PhaseRunning, PhaseReloading, nil) {
+		return ErrServiceNotRunning
+	}
+
+	lc.ctx.Info("Reloading")
+	started := time.Now()
+
+	err := h.callService(ctx, lc, PhaseReloading, r.Reload)
+	h.lastReload.Store(&reloadResult{time: time.Now(), err: err})
+
+	if err != nil {
+		lc.ctx.Error("Reload failed", "error", err, "duration", time.Since(started).String())
+	} else {
+		lc.ctx.Info("Reloaded", "duration", time.Since(started).String())
+	}
+
+	// the service may have stopped running while reloading
+	h.compareAndSetPhase(PhaseReloading, PhaseRunning, err)
+
+	return err
+}
+
+// callService calls fn with the context of the given lifecycle, which is additionally canceled once ctx is done,
+// e.g. to reload, pause or resume the service in the given phase. A panic in fn is recovered as a *PanicError.
+func (h *Handle) callService(ctx context.Context, lc *lifecycle, phase Phase, fn func(*Context) error) (err error) {
+	callCtx, cancel := context.WithCancelCause(lc.ctx.Context)
+	defer cancel(nil)
+	stop := context.AfterFunc(ctx, func() {
+		cancel(context.Cause(ctx))
+	})
+	defer stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(h.String(), phase, r)
+		}
+	}()
+
+	return fn(lc.ctx.withContext(callCtx))
+}
+
+// LastReload returns the time and the error of the last reload of the service instance.
+// The time is zero if the service has not been reloaded yet.
+func (h *Handle) LastReload() (time.Time, error) {
+	result := h.lastReload.Load()
+	if result == nil {
+		return time.Time{}, nil
+	}
+
+	return result.time, result.err
+}
+
+// Reload reloads all running services of the runner that implement Reloadable, in the order they were started.
+// Services that do not implement Reloadable or are not running are skipped.
+func (r *Runner) Reload(ctx context.Context) error {
+	return fail.WrapMany("failed to reload runner", reloadHandles(ctx, r.Handles())...)
+}
+
+// reloadHandles reloads the given handles in order, skipping services that do not implement Reloadable
+// or are not running. It returns the errors of all failed reloads.
+func reloadHandles(ctx context.Context, handles []*Handle) []error {
+	var errs []error
+	for _, h := range handles {
+		if _, ok := h.svc.(Reloadable); !ok || h.Phase() != PhaseRunning {
+			continue
+		}
+
+		if err := h.Reload(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// handleReloadSignals reloads the services of the runner whenever one of the given signals is received.
+// It returns once the runner has finished waiting for its services.
+func (r *Runner) handleReloadSignals(signals []os.Signal) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case sig := <-sigCh:
+			serviceLogger(r.ctx, runnerEnvPrefix(r.ctx)).Info("Received signal, reloading", "signal", sig.String())
+
+			// failures are logged by the services themselves
+			_ = r.Reload(r.ctx)
+		case <-r.done:
+			return
+		}
+	}
+}
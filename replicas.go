@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/fail"
+	"github.com/google/uuid"
+)
+
+// instanceKey is the context key type for storing the Instance.
+type instanceKey struct{}
+
+// Instance identifies a single replica of a service run using Replicas.
+type Instance struct {
+	// Index is the index of the replica, starting at 0.
+	Index int
+	// ID is the unique ID of the replica, a random UUID. It is reported as the service.instance.id
+	// attribute in logs and the OpenTelemetry resource.
+	ID string
+}
+
+// WithInstance returns a new context with the specified Instance.
+// Services run with the returned context are identified as the given replica.
+func WithInstance(ctx context.Context, instance Instance) context.Context {
+	return context.WithValue(ctx, instanceKey{}, instance)
+}
+
+// InstanceFromContext retrieves the Instance from the context, if present.
+// The boolean reports whether the context belongs to a replica.
+func InstanceFromContext(ctx context.Context) (Instance, bool) {
+	instance, ok := ctx.Value(instanceKey{}).(Instance)
+	return instance, ok
+}
+
+// InstanceIndex returns the index of the replica the Context belongs to, see Replicas.
+// It returns 0 if the Context does not belong to a replica.
+func (c *Context) InstanceIndex() int {
+	instance, _ := InstanceFromContext(c)
+	return instance.Index
+}
+
+// InstanceID returns the unique ID of the replica the Context belongs to, see Replicas.
+// It returns an empty string if the Context does not belong to a replica.
+func (c *Context) InstanceID() string {
+	instance, _ := InstanceFromContext(c)
+	return instance.ID
+}
+
+// ReplicaSet is a Service that runs multiple replicas of another service, e.g. multiple consumers of a queue.
+// It is created using Replicas.
+//
+// Each replica is run independently, i.e. a failing replica does not affect the others, and is supervised
+// according to the Supervision of the context. Replicas are identified by their Instance, which is stable
+// across restarts of a replica and available using Context.InstanceIndex and Context.InstanceID.
+// The ReplicaSet only returns once all replicas have exited, or when it is shut down.
+//
+// The identity of the set and its dependencies (see Dependent), shutdown timeout (see ShutdownTimeoutProvider)
+// and restart policy (see RestartPolicyProvider) are those of the replicated service. Draining the set drains
+// all replicas, see Drainer.
+//
+// The Health of a ReplicaSet aggregates the health of its replicas, which is reported as a map from
+// "name[index]" to the Health of each replica in the details.
+type ReplicaSet struct {
+	factory func(index int) Service
+
+	// firstOnce ensures the first instance is created at most once.
+	firstOnce sync.Once
+	// first is the first instance, which determines the identity of the set. It is created lazily.
+	first Service
+
+	mtx sync.Mutex
+	// desired is the number of replicas that should be running.
+	desired int
+	// ids contains the IDs of the instances by index, so that they are stable across restarts.
+	ids []string
+	// firstUsed is set once the first instance has been run as a replica.
+	// Replicas are created using the factory afterwards.
+	firstUsed bool
+	// ctx is the context replicas are run with, or nil if the set is not running.
+	ctx context.Context
+	// replicas contains the currently running replicas, ordered by index.
+	replicas []*replica
+	// changed is signaled whenever a replica has exited.
+	changed chan struct{}
+}
+
+// replica is a single replica of a ReplicaSet.
+type replica struct {
+	instance Instance
+	runner   *Runner
+	handle   *Handle
+
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// Replicas returns a ReplicaSet running n replicas of the service returned by factory.
+// The factory is called with the index of each replica and must return a new instance of the service.
+// The identity of the set, i.e. its name, namespace and version, is taken from the first instance,
+// which is created once the identity is first needed and used as the first replica.
+// The number of replicas can be changed at runtime using ReplicaSet.Scale.
+func Replicas(n int, factory func(index int) Service) *ReplicaSet {
+	return &ReplicaSet{
+		factory: factory,
+		desired: max(n, 0),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Name returns the name of the replicated service.
+func (s *ReplicaSet) Name() string {
+	return s.template().Name()
+}
+
+// Namespace returns the namespace of the replicated service.
+func (s *ReplicaSet) Namespace() string {
+	return s.template().Namespace()
+}
+
+// Version returns the version of the replicated service.
+func (s *ReplicaSet) Version() string {
+	return s.template().Version()
+}
+
+// Dependencies returns the dependencies of the replicated service, see Dependent.
+// The replicas are only started once the dependencies of the set are ready.
+func (s *ReplicaSet) Dependencies() []Dependency {
+	if d, ok := s.template().(Dependent); ok {
+		return d.Dependencies()
+	}
+
+	return nil
+}
+
+// ShutdownTimeout returns the shutdown timeout of the replicated service, see ShutdownTimeoutProvider.
+// It returns zero if the replicated service does not define one, so the timeout of the context is used.
+func (s *ReplicaSet) ShutdownTimeout() time.Duration {
+	if p, ok := s.template().(ShutdownTimeoutProvider); ok {
+		return p.ShutdownTimeout()
+	}
+
+	return 0
+}
+
+// RestartPolicy returns the restart policy of the replicated service, see RestartPolicyProvider.
+// If the replicated service does not define one, RestartNever is returned, as each replica is already
+// restarted according to the Supervision of the context.
+func (s *ReplicaSet) RestartPolicy() RestartPolicy {
+	if p, ok := s.template().(RestartPolicyProvider); ok {
+		return p.RestartPolicy()
+	}
+
+	return RestartNever
+}
+
+// Drain drains all running replicas concurrently, see Drainer.
+// Replicas that implement Drainer are drained themselves, and each replica observes its drain delay.
+func (s *ReplicaSet) Drain(ctx *Context) error {
+	s.mtx.Lock()
+	replicas := s.replicas
+	s.mtx.Unlock()
+
+	wg := sync.WaitGroup{}
+	errs := make([]error, len(replicas))
+	for i, r := range replicas {
+		lc := r.handle.getLifecycle()
+		if lc == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.handle.drain(ctx, lc)
+		}()
+	}
+
+	wg.Wait()
+
+	errs = slices.DeleteFunc(errs, func(err error) bool {
+		return err == nil
+	})
+
+	return fail.WrapMany("failed to drain replicas", errs...)
+}
+
+// template returns the first instance of the replicated service, creating it if necessary.
+func (s *ReplicaSet) template() Service {
+	s.firstOnce.Do(func() {
+		s.first = s.factory(0)
+	})
+
+	return s.first
+}
+
+// Replicas returns the number of replicas that should be running.
+func (s *ReplicaSet) Replicas() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.desired
+}
+
+// Handles returns the handles of the currently running replicas, ordered by their index.
+func (s *ReplicaSet) Handles() []*Handle {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	handles := make([]*Handle, len(s.replicas))
+	for i, r := range s.replicas {
+		handles[i] = r.handle
+	}
+
+	return handles
+}
+
+// Health returns the aggregated health of the replicas.
+func (s *ReplicaSet) Health() Health {
+	s.mtx.Lock()
+	if s.ctx == nil {
+		s.mtx.Unlock()
+		return Health{Status: HealthStatusUnknown}
+	}
+	replicas := s.replicas
+	s.mtx.Unlock()
+
+	healths := make(map[string]Health, len(replicas))
+	for _, r := range replicas {
+		healths[fmt.Sprintf("%s[%d]", s.Name(), r.instance.Index)] = r.handle.Health()
+	}
+
+	return aggregateHealth(healths)
+}
+
+// Initialize prepares the service for execution.
+// The replicas are initialized when the set is run.
+func (s *ReplicaSet) Initialize(_ *Context) error {
+	return nil
+}
+
+// Run runs the replicas and blocks until all of them have exited, or the set is shut down.
+// Returns an error wrapping the errors of all failed replicas.
+func (s *ReplicaSet) Run(ctx *Context) error {
+	s.mtx.Lock()
+	// the set may have been shut down before its replicas were started
+	if ctx.Err() != nil {
+		s.mtx.Unlock()
+		return nil
+	}
+
+	s.ctx = childContext(ctx)
+	s.replicas = nil
+	err := s.scaleUp(s.desired)
+	s.mtx.Unlock()
+
+	if err != nil {
+		_ = s.stopAll(context.WithoutCancel(ctx))
+		return err
+	}
+
+	for {
+		if s.allExited() {
+			return s.wait()
+		}
+
+		select {
+		case <-ctx.Done():
+			// If the context was canceled for another reason than a shutdown request,
+			// Shutdown is not called before Run returns.
+			var shutdownErr *ShutdownRequestedError
+			if !errors.As(context.Cause(ctx), &shutdownErr) {
+				_ = s.stopAll(context.WithoutCancel(ctx))
+			}
+
+			return s.wait()
+		case <-s.changed:
+		}
+	}
+}
+
+// Shutdown shuts down all replicas, using the provided context for cancellation and timeout.
+func (s *ReplicaSet) Shutdown(ctx *Context) error {
+	return s.stopAll(ctx)
+}
+
+// Reload reloads all running replicas that implement Reloadable.
+func (s *ReplicaSet) Reload(ctx *Context) error {
+	return fail.WrapMany("failed to reload replicas", reloadHandles(ctx, s.Handles())...)
+}
+
+// Scale changes the number of replicas to n. If the set is running, replicas are started or shut down
+// accordingly, where replicas with the highest indexes are shut down first. Scale waits for replicas
+// that are shut down to exit, using the provided context for cancellation and timeout.
+// If the set is not running, the number of replicas is applied the next time it is run.
+// A running set that is scaled to zero replicas returns once all of its replicas have exited.
+func (s *ReplicaSet) Scale(ctx context.Context, n int) error {
+	if n < 0 {
+		return fail.New().
+			Attribute("replicas", n).
+			Msgf("invalid number of replicas %d", n)
+	}
+
+	s.mtx.Lock()
+	s.desired = n
+	if s.ctx == nil {
+		s.mtx.Unlock()
+		return nil
+	}
+
+	err := s.scaleUp(n)
+
+	var removed []*replica
+	if len(s.replicas) > n {
+		removed = s.replicas[n:]
+		s.replicas = s.replicas[:n:n]
+	}
+	s.mtx.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return stopReplicas(ctx, removed)
+}
+
+// scaleUp starts replicas until n replicas are running.
+// The caller must hold s.mtx.
+func (s *ReplicaSet) scaleUp(n int) error {
+	for len(s.replicas) < n {
+		r, err := s.startReplica(len(s.replicas))
+		if err != nil {
+			return err
+		}
+
+		s.replicas = append(s.replicas, r)
+	}
+
+	return nil
+}
+
+// startReplica starts the replica with the given index.
+// The caller must hold s.mtx.
+func (s *ReplicaSet) startReplica(index int) (*replica, error) {
+	for len(s.ids) <= index {
+		s.ids = append(s.ids, uuid.NewString())
+	}
+
+	var svc Service
+	if index == 0 && !s.firstUsed {
+		svc = s.template()
+		s.firstUsed = true
+	} else {
+		svc = s.factory(index)
+	}
+
+	instance := Instance{Index: index, ID: s.ids[index]}
+	r := &replica{
+		instance: instance,
+		runner:   NewRunner(WithInstance(s.ctx, instance)),
+	}
+	// the dependencies of the replicas are those of the set, which are ready before the set runs
+	r.runner.ignoreDependencies = true
+
+	// a fresh runner is always usable, so adding cannot fail
+	_ = r.runner.Add(svc)
+	if err := r.runner.Start(); err != nil {
+		return nil, fail.New().
+			Attribute("instance.index", index).
+			Cause(err).
+			Msgf("failed to start replica %d of service %s", index, s.Name())
+	}
+
+	r.handle = r.runner.Handles()[0]
+	go func() {
+		<-r.handle.exitSig
+
+		select {
+		case s.changed <- struct{}{}:
+		default:
+		}
+	}()
+
+	return r, nil
+}
+
+// allExited reports whether all replicas have exited, which is the case if the set has no replicas.
+func (s *ReplicaSet) allExited() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, r := range s.replicas {
+		if !r.handle.hasExited() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// stopAll shuts down all replicas.
+func (s *ReplicaSet) stopAll(ctx context.Context) error {
+	s.mtx.Lock()
+	replicas := s.replicas
+	s.mtx.Unlock()
+
+	return stopReplicas(ctx, replicas)
+}
+
+// wait waits for all replicas to exit and marks the set as not running.
+// Returns an error wrapping the errors of all failed replicas.
+func (s *ReplicaSet) wait() error {
+	s.mtx.Lock()
+	replicas := s.replicas
+	s.mtx.Unlock()
+
+	var errs []error
+	for _, r := range replicas {
+		if err := r.handle.Wait(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	s.mtx.Lock()
+	s.ctx = nil
+	s.mtx.Unlock()
+
+	return fail.WrapMany("one or more replicas failed", errs...)
+}
+
+// stopReplicas shuts down the given replicas concurrently.
+func stopReplicas(ctx context.Context, replicas []*replica) error {
+	wg := sync.WaitGroup{}
+	errs := make([]error, len(replicas))
+	for i, r := range replicas {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs[i] = r.stop(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	errs = slices.DeleteFunc(errs, func(err error) bool {
+		return err == nil
+	})
+
+	return fail.WrapMany("failed to stop replicas", errs...)
+}
+
+// stop shuts down the replica at most once.
+func (r *replica) stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		r.stopErr = r.runner.Stop(ctx)
+	})
+
+	return r.stopErr
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/FlowSeer/fail"
 	"github.com/joho/godotenv"
@@ -13,6 +15,7 @@ import (
 	"go.opentelemetry.io/contrib/bridges/otelslog"
 	"go.opentelemetry.io/contrib/instrumentation/host"
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/log"
 	logNoop "go.opentelemetry.io/otel/log/noop"
 	"go.opentelemetry.io/otel/metric"
@@ -25,50 +28,44 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 	traceNoop "go.opentelemetry.io/otel/trace/noop"
-	"golang.org/x/sync/errgroup"
 )
 
 // RunAndExit runs the given service using the provided context, waits for it to finish,
 // and then exits the process with an appropriate exit code based on the error returned.
 // If the service completes successfully, the process exits with code 0.
-// If an error occurs, the process exits with the code returned by fail.ExitCode(err).
+// If an error occurs, the process exits with the code decided by the ExitPolicy of the context, see WithExitPolicy.
+// Signal handling is enabled using the default shutdown signals, unless configured otherwise using WithShutdownSignals.
 func RunAndExit(ctx context.Context, svc Service) {
-	err := RunAndWait(ctx, svc)
+	err := RunAndWait(withDefaultShutdownSignals(ctx), svc)
 	if err != nil {
 		fail.PrintPretty(err)
-		os.Exit(fail.ExitCode(err))
+		os.Exit(ExitPolicyFromContext(ctx).ExitCode(err))
 	} else {
 		os.Exit(0)
 	}
 }
 
 // RunParallelAndExit runs multiple services in parallel using the provided context,
-// waits for all of them to finish, and then exits the process with the highest exit code
-// among all returned errors. If all services complete successfully, the process exits with code 0.
+// waits for all of them to finish, and then exits the process with the exit code decided by the
+// ExitPolicy of the context for all returned errors, see WithExitPolicy. By default, this is the highest exit code.
+// If all services complete successfully, the process exits with code 0.
+// Signal handling is enabled using the default shutdown signals, unless configured otherwise using WithShutdownSignals.
 func RunParallelAndExit(ctx context.Context, svcs ...Service) {
-	errs := RunParallelAndWait(ctx, svcs...)
-
-	exitCode := 0
-	for _, err := range errs {
-		exitCode = max(exitCode, fail.ExitCode(err))
-	}
+	errs := RunParallelAndWait(withDefaultShutdownSignals(ctx), svcs...)
 
-	os.Exit(exitCode)
+	os.Exit(ExitPolicyFromContext(ctx).ExitCode(errs...))
 }
 
 // RunGroupAndExit runs multiple services as a group using the provided context,
 // where the group is canceled if any service returns an error. It waits for all services
-// to finish and then exits the process with the highest exit code among all returned errors.
+// to finish and then exits the process with the exit code decided by the ExitPolicy of the context
+// for all returned errors, see WithExitPolicy. By default, this is the highest exit code.
 // If all services complete successfully, the process exits with code 0.
+// Signal handling is enabled using the default shutdown signals, unless configured otherwise using WithShutdownSignals.
 func RunGroupAndExit(ctx context.Context, svcs ...Service) {
-	errs := RunGroupAndWait(ctx, svcs...)
+	errs := RunGroupAndWait(withDefaultShutdownSignals(ctx), svcs...)
 
-	exitCode := 0
-	for _, err := range errs {
-		exitCode = max(exitCode, fail.ExitCode(err))
-	}
-
-	os.Exit(exitCode)
+	os.Exit(ExitPolicyFromContext(ctx).ExitCode(errs...))
 }
 
 // RunAndWait runs the given service using the provided context and waits for it to finish.
@@ -134,6 +131,7 @@ func RunGroupAndWait(ctx context.Context, svcs ...Service) []error {
 
 // Run runs the given service using the provided context and returns a Handle
 // that can be used to wait for the service to finish or to shut it down.
+// Signal handling can be enabled using WithShutdownSignals.
 func Run(ctx context.Context, svc Service) *Handle {
 	return RunParallel(ctx, svc)[0]
 }
@@ -152,92 +150,229 @@ func RunGroup(ctx context.Context, svcs ...Service) []*Handle {
 	return runAll(ctx, true, svcs)
 }
 
-// runAll runs the services using the provided context and error group.
-// if any service returns an error. Returns a slice of Handles for the running services.
+// runAll runs the services using a Runner that is grouped if requested.
+// Returns a slice of Handles for the running services.
 func runAll(ctx context.Context, grouped bool, svcs []Service) []*Handle {
-	eg := &errgroup.Group{} // empty group is valid and implies no cancellation on error
-	if grouped {
-		eg, ctx = errgroup.WithContext(ctx)
+	r := newRunner(ctx, grouped)
+	// a fresh runner is always usable, so adding cannot fail
+	_ = r.Add(svcs...)
+	if err := r.Start(); err != nil {
+		// the services could not be started, e.g. due to invalid dependencies
+		handles := make([]*Handle, len(svcs))
+		for i, svc := range svcs {
+			handles[i] = createErrorHandle(svc, err)
+		}
+
+		return handles
 	}
 
-	handles := make([]*Handle, len(svcs))
-	for i, svc := range svcs {
-		handles[i] = run(ctx, eg, svc)
+	_, shutdownSignals := ShutdownSignals(ctx)
+	_, reloadSignals := ReloadSignals(ctx)
+	_, healthServer := HealthServer(ctx)
+	if shutdownSignals || reloadSignals || healthServer {
+		// the runner is not exposed, so wait in the background to end signal handling
+		// and the health server once all services have finished
+		go func() {
+			_ = r.Wait()
+		}()
 	}
 
-	return handles
+	return r.Handles()
 }
 
-// run runs the given service using the provided context and returns a Handle
-// that can be used to wait for the service to finish or to shut it down.
-// The service is being run in parallel using the provided error group.
-func run(ctx context.Context, eg *errgroup.Group, svc Service) *Handle {
+// prepare creates the context and the Handle for running the given service as part of the given runner,
+// without starting the service. If the context cannot be created, an already exited Handle is returned.
+func prepare(r *Runner, svc Service) *Handle {
 	_ = godotenv.Load()
 
-	svcCtx, err := createContext(ctx, svc)
+	svcCtx, err := createContext(r.ctx, svc)
 	if err != nil {
 		return createErrorHandle(svc, err)
 	}
 
-	handle := createHandle(svc, svcCtx)
-	eg.Go(func() error {
-		svcErr := runBlocking(svcCtx, svc, handle)
+	return createHandle(svc, svcCtx, r.supervisor, r.escalate)
+}
+
+// run runs the service managed by the given handle as part of the given runner.
+// The service is being run in parallel using the error group of the runner,
+// and is restarted by the supervisor of the runner as configured.
+func run(r *Runner, handle *Handle) {
+	if handle.hasExited() {
+		return
+	}
+
+	r.eg.Go(func() error {
+		svcErr := handle.waitForDependencies()
+		if svcErr == nil {
+			svcErr = r.supervisor.supervise(handle)
+		} else {
+			handle.setPhase(PhaseFailed, svcErr)
+		}
+
+		if shutdownErr := shutdownTelemetry(handle); shutdownErr != nil {
+			svcErr = withAssociated(svcErr, shutdownErr)
+		}
 		handle.setStopped(svcErr)
 
 		return svcErr
 	})
-
-	return handle
 }
 
-func runBlocking(ctx *Context, svc Service, handle *Handle) error {
+// runBlocking runs a single lifecycle of the given service, i.e. initializes, runs and shuts it down.
+// It returns the error of the lifecycle, with any shutdown error associated.
+// Errors are reported as *InitError, *RunError and *ShutdownError, and panics are recovered as *PanicError.
+func runBlocking(ctx *Context, svc Service, handle *Handle, lc *lifecycle) error {
 	ctx.Logger().Debug("Initializing")
-	handle.setPhase(PhaseInitializing)
-
-	err := svc.Initialize(ctx)
-	if err != nil {
-		return err
-	}
-
-	ctx.Logger().Debug("Running")
-	handle.setPhase(PhaseRunning)
+	handle.setPhase(PhaseInitializing, nil)
 
-	runFn := func() (err error) {
+	started := time.Now()
+	initFn := func() (err error) {
 		defer func() {
 			if r := recover(); r != nil {
-				switch x := r.(type) {
-				case error:
-					err = fail.From(x).
-						Associate(err).
-						Msg("service panicked")
-				default:
-					err = fail.New().
-						Cause(fail.Msgf("%v", x)).
-						Associate(err).
-						Msg("service panicked")
-				}
+				err = newPanicError(handle.String(), PhaseInitializing, r)
 			}
 		}()
 
-		return svc.Run(ctx)
+		return svc.Initialize(ctx)
 	}
 
-	err = runFn()
+	// the lifecycle may be abandoned while initializing or running if it does not stop in time for a restart
+	err := lc.await(initFn)
+	if lc.isAbandoned() {
+		return lc.abandonErr
+	}
 
-	ctx.Logger().Debug("Shutting down")
-	handle.setPhase(PhaseShuttingDown)
+	if err != nil {
+		err = &InitError{
+			Service:  handle.String(),
+			Phase:    PhaseInitializing,
+			Duration: time.Since(started),
+			Err:      err,
+		}
+
+		lc.cancel(err)
+		lc.setRunDone()
+
+		abortStarted := time.Now()
+		err = withAssociated(err, shutdownError(handle, abortStarted, handle.abortLifecycle(lc)))
+		handle.setPhase(PhaseFailed, err)
+		return err
+	}
+
+	// the service may have been shut down while initializing
+	if !handle.isStopping() {
+		ctx.Logger().Debug("Running")
+		handle.setPhase(PhaseRunning, nil)
+		handle.started.Store(true)
+
+		started = time.Now()
+		runFn := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = newPanicError(handle.String(), PhaseRunning, r)
+				}
+			}()
 
-	shutdownErr := handle.Shutdown(ctx)
-	if shutdownErr != nil {
-		handle.setPhase(PhaseFailed)
+			return svc.Run(ctx)
+		}
+
+		err = lc.await(runFn)
+		if lc.isAbandoned() {
+			return lc.abandonErr
+		}
+
+		if err != nil {
+			err = &RunError{
+				Service:  handle.String(),
+				Phase:    PhaseRunning,
+				Duration: time.Since(started),
+				Err:      err,
+			}
+		}
+	}
+	lc.setRunDone()
+
+	ctx.Logger().Debug("Shutting down")
+	handle.setPhase(PhaseShuttingDown, err)
+
+	// the service context may already be canceled, e.g. because another service in the group failed,
+	// which must not abort the shutdown of this service.
+	// The lifecycle is only done once all background tasks have returned and all shutdown hooks have been called.
+	shutdownStarted := time.Now()
+	shutdownErr := handle.stopLifecycle(context.WithoutCancel(ctx), lc, ctx.tasks.wait(), true)
+
+	if taskErr := ctx.tasks.err(); taskErr != nil && err == nil {
+		err = &RunError{
+			Service:  handle.String(),
+			Phase:    PhaseRunning,
+			Duration: shutdownStarted.Sub(started),
+			Err:      taskErr,
+		}
 	} else {
-		handle.setPhase(PhaseFinished)
+		err = withAssociated(err, taskErr)
 	}
 
+	err = withAssociated(err, shutdownError(handle, shutdownStarted, shutdownErr))
 	if err != nil {
-		return fail.WithAssociated(err, shutdownErr)
+		handle.setPhase(PhaseFailed, err)
 	} else {
-		return shutdownErr
+		handle.setPhase(PhaseFinished, nil)
+	}
+
+	return err
+}
+
+// shutdownError wraps the given error encountered while shutting down the service managed by the handle
+// in a *ShutdownError, or returns nil if err is nil.
+func shutdownError(handle *Handle, started time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ShutdownError{
+		Service:  handle.String(),
+		Phase:    PhaseShuttingDown,
+		Duration: time.Since(started),
+		Err:      err,
+	}
+}
+
+// shutdownTelemetry shuts down the OpenTelemetry providers of the service managed by the handle,
+// flushing any buffered telemetry. It is bounded by the shutdown timeout of the service.
+func shutdownTelemetry(handle *Handle) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(handle.svcContext), handle.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	if err := handle.svcContext.meterShutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := handle.svcContext.tracerShutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return fail.WrapMany("Shutdown encountered an error", errs...)
+}
+
+// withAssociated returns err with the given associated error attached.
+// Unlike fail.WithAssociated, it returns associated if err is nil, and err unchanged if associated is nil.
+// Errors that are not a fail.Fail are preserved, so that they can still be retrieved using errors.As.
+func withAssociated(err error, associated error) error {
+	if err == nil {
+		return associated
+	}
+	if associated == nil {
+		return err
+	}
+
+	switch e := err.(type) {
+	case fail.Fail:
+		return fail.WithAssociated(e, associated)
+	case *associatedError:
+		return &associatedError{err: e.err, associated: append(slices.Clone(e.associated), associated)}
+	default:
+		return &associatedError{err: e, associated: []error{associated}}
 	}
 }
 
@@ -252,13 +387,34 @@ func createContext(ctx context.Context, svc Service) (*Context, error) {
 		"service.namespace": svc.Namespace(),
 	})
 
-	logger := LoggerFromEnv(svc.Name()).
+	opts := RunOptionsFromContext(ctx)
+	prefix := envPrefix(ctx, svc)
+
+	logger := serviceLogger(ctx, prefix).
 		With("service.name", svc.Name(),
 			"service.version", svc.Version())
 	if svc.Namespace() != "" {
 		logger = logger.With("service.namespace", svc.Namespace())
 	}
 
+	resourceAttrs := []attribute.KeyValue{
+		semconv.ServiceName(svc.Name()),
+		semconv.ServiceVersion(svc.Version()),
+		semconv.ServiceNamespace(svc.Namespace()),
+	}
+
+	// replicas are distinguished by their instance
+	if instance, ok := InstanceFromContext(ctx); ok {
+		logger = logger.With(
+			"service.instance.id", instance.ID,
+			"service.instance.index", instance.Index)
+		ctx = fail.ContextAddAttributes(ctx, map[string]any{
+			"service.instance.id":    instance.ID,
+			"service.instance.index": instance.Index,
+		})
+		resourceAttrs = append(resourceAttrs, semconv.ServiceInstanceID(instance.ID))
+	}
+
 	ctx = WithLogger(ctx, logger)
 
 	var (
@@ -268,44 +424,40 @@ func createContext(ctx context.Context, svc Service) (*Context, error) {
 		meterShutdown                       = OtelNoopShutdown
 		loggerProvider log.LoggerProvider   = logNoop.NewLoggerProvider()
 		loggerShutdown                      = OtelNoopShutdown
+		// collectMetrics is set if a meter provider other than the noop provider is in effect.
+		collectMetrics bool
 	)
 
 	// OTEL is opt-in, but individual components must be enabled explicitly
-	if IsOtelEnabled(svc.Name()) {
-		res, err := resource.New(ctx, resource.WithAttributes(
-			semconv.ServiceName(svc.Name()),
-			semconv.ServiceVersion(svc.Version()),
-			semconv.ServiceNamespace(svc.Namespace()),
-		))
+	if IsOtelEnabled(prefix) {
+		res, err := resource.New(ctx, resource.WithAttributes(resourceAttrs...))
 		if err != nil {
 			return nil, fail.Wrap(err, "failed to create OTEL resource")
 		}
 
-		if !IsOtelMetricsDisabled(svc.Name()) {
-			meterProvider, meterShutdown, err = MeterProviderFromEnv(ctx, metricSdk.WithResource(res))
+		if opts.Resource != nil {
+			res, err = resource.Merge(opts.Resource, res)
 			if err != nil {
-				return nil, fail.Wrap(err, "failed to create OTEL meter provider")
-			}
-
-			err = runtime.Start(runtime.WithMeterProvider(meterProvider))
-			if err != nil {
-				return nil, fail.Wrap(err, "failed to start collection of runtime metrics")
+				return nil, fail.Wrap(err, "failed to merge OTEL resource")
 			}
+		}
 
-			err = host.Start(host.WithMeterProvider(meterProvider))
+		if opts.MeterProvider == nil && !IsOtelMetricsDisabled(prefix) {
+			meterProvider, meterShutdown, err = MeterProviderFromEnv(ctx, metricSdk.WithResource(res))
 			if err != nil {
-				return nil, fail.Wrap(err, "failed to start collection of host metrics")
+				return nil, fail.Wrap(err, "failed to create OTEL meter provider")
 			}
+			collectMetrics = true
 		}
 
-		if !IsOtelTracesDisabled(svc.Name()) {
+		if opts.TracerProvider == nil && !IsOtelTracesDisabled(prefix) {
 			tracerProvider, tracerShutdown, err = TracerProviderFromEnv(ctx, traceSdk.WithResource(res))
 			if err != nil {
 				return nil, fail.Wrap(err, "failed to create OTEL tracer provider")
 			}
 		}
 
-		if !IsOtelLogsDisabled(svc.Name()) {
+		if !IsOtelLogsDisabled(prefix) {
 			loggerProvider, loggerShutdown, err = LoggerProviderFromEnv(ctx, logSdk.WithResource(res))
 			if err != nil {
 				return nil, fail.Wrap(err, "failed to create OTEL logger provider")
@@ -322,22 +474,45 @@ func createContext(ctx context.Context, svc Service) (*Context, error) {
 				),
 			))
 		}
-	} else {
+	} else if opts.TracerProvider == nil && opts.MeterProvider == nil {
 		logger.Warn(fmt.Sprintf(
 			"Set env %s=true to enable OpenTelemetry.",
-			EnvName(svc.Name(), OtelEnableEnvVar)),
+			EnvName(prefix, OtelEnableEnvVar)),
 		)
 	}
 
+	// providers passed as RunOptions are owned by the caller, so they are not shut down
+	if opts.TracerProvider != nil {
+		tracerProvider = opts.TracerProvider
+	}
+	if opts.MeterProvider != nil {
+		meterProvider = opts.MeterProvider
+		collectMetrics = true
+	}
+
+	// runtime and host metrics are collected using the meter provider in effect, whether created or passed
+	if collectMetrics {
+		if err := runtime.Start(runtime.WithMeterProvider(meterProvider)); err != nil {
+			return nil, fail.Wrap(err, "failed to start collection of runtime metrics")
+		}
+
+		if err := host.Start(host.WithMeterProvider(meterProvider)); err != nil {
+			return nil, fail.Wrap(err, "failed to start collection of host metrics")
+		}
+	}
+
 	ctx = WithTracerProvider(ctx, tracerProvider)
 	ctx = WithMeterProvider(ctx, meterProvider)
 	ctx = WithLoggerProvider(ctx, loggerProvider)
 
 	// We explicitly do NOT set the propagator globally, as multiple services may use different ones
 	// Right now, all services have the same propagator behaviour, but this leaves the option to change it later
-	propagator := propagation.NewCompositeTextMapPropagator(
+	var propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{}, propagation.Baggage{},
 	)
+	if opts.Propagator != nil {
+		propagator = opts.Propagator
+	}
 	ctx = WithTextMapPropagator(ctx, propagator)
 
 	tracer := tracerProvider.Tracer(InstrumentationName, trace.WithInstrumentationVersion(InstrumentationVersion))
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/FlowSeer/fail"
+	"golang.org/x/sync/errgroup"
+)
+
+// Runner manages a set of services as a single unit.
+// Services are added to the runner using Add and are started using Start.
+// Wait blocks until all services have finished, and Stop gracefully shuts down all services.
+//
+// A Runner is either grouped or not. In a grouped runner, the context of all services is canceled
+// as soon as any service returns an error. In a non-grouped runner, services run independently.
+// Services are restarted according to the Supervision of the runner context, see WithSupervision.
+//
+// Once a Runner has been stopped, or Wait has returned, it can no longer be used to add or start services.
+// Wait may still be called after Stop to wait for the services to exit.
+type Runner struct {
+	// ctx is the context the services are run with.
+	ctx context.Context
+	// eg is the error group used to run the services.
+	eg *errgroup.Group
+	// supervisor restarts the services of the runner as configured.
+	supervisor *supervisor
+	// done is closed once Wait has finished waiting for all services.
+	done chan struct{}
+	// ignoreDependencies is set if the dependencies of the services are handled by the parent of the runner,
+	// e.g. a ReplicaSet, so that they are not resolved among the services of the runner.
+	ignoreDependencies bool
+	// signalOnce ensures signal handling and the health server are set up at most once.
+	signalOnce sync.Once
+
+	mtx sync.Mutex
+	// pending contains the services that have been added but not yet started.
+	pending []Service
+	// handles contains the handles of all started services, in the order they were started.
+	handles []*Handle
+
+	started  bool
+	waiting  bool
+	stopped  bool
+	finished bool
+}
+
+// NewRunner creates a new Runner whose services run independently using the provided context.
+// A service returning an error does not affect any other service managed by the runner.
+func NewRunner(ctx context.Context) *Runner {
+	return newRunner(ctx, false)
+}
+
+// NewGroupRunner creates a new Runner whose services run as a group using the provided context.
+// If any service returns an error, the context is canceled for all services managed by the runner.
+func NewGroupRunner(ctx context.Context) *Runner {
+	return newRunner(ctx, true)
+}
+
+// newRunner creates a new Runner using the provided context.
+func newRunner(ctx context.Context, grouped bool) *Runner {
+	eg := &errgroup.Group{} // empty group is valid and implies no cancellation on error
+	if grouped {
+		eg, ctx = errgroup.WithContext(ctx)
+	}
+
+	r := &Runner{
+		ctx:  ctx,
+		eg:   eg,
+		done: make(chan struct{}),
+	}
+	r.supervisor = &supervisor{
+		runner:      r,
+		supervision: SupervisionFromContext(ctx),
+	}
+
+	return r
+}
+
+// Add adds the given services to the runner.
+// If the runner has already been started, the services are started immediately,
+// and an error is returned if their dependencies are invalid (see Dependent).
+// Returns ErrRunnerWaiting if the runner is currently waiting for its services to finish,
+// or ErrRunnerStopped if the runner has been stopped.
+func (r *Runner) Add(svcs ...Service) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err := r.checkUsable(); err != nil {
+		return err
+	}
+
+	if r.started {
+		return r.startAll(svcs)
+	}
+
+	r.pending = append(r.pending, svcs...)
+	return nil
+}
+
+// Start starts all services that have been added to the runner but not yet started.
+// Services added after Start has been called are started immediately by Add.
+// If a dependency of a service cannot be found or the dependencies form a cycle,
+// none of the services are started and an error is returned (see Dependent).
+// Returns ErrRunnerWaiting if the runner is currently waiting for its services to finish,
+// or ErrRunnerStopped if the runner has been stopped.
+func (r *Runner) Start() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if err := r.checkUsable(); err != nil {
+		return err
+	}
+
+	return r.start()
+}
+
+// Wait blocks until all services managed by the runner have finished.
+// If the runner has not been started yet, Wait starts it first.
+// If signal handling or the health server is enabled, it stays active until Wait returns.
+// Returns an error wrapping the errors of all failed services, or nil if all services completed successfully.
+// Once Wait returns, the runner is stopped.
+// Returns ErrRunnerAlreadyWaiting if another call to Wait is in progress,
+// or ErrRunnerStopped if a previous call to Wait has already returned.
+func (r *Runner) Wait() error {
+	r.mtx.Lock()
+	if r.waiting {
+		r.mtx.Unlock()
+		return ErrRunnerAlreadyWaiting
+	}
+	if r.finished {
+		r.mtx.Unlock()
+		return ErrRunnerStopped
+	}
+
+	if !r.stopped {
+		if err := r.start(); err != nil {
+			r.mtx.Unlock()
+			return err
+		}
+	}
+	r.waiting = true
+	handles := slices.Clone(r.handles)
+	r.mtx.Unlock()
+
+	// Handles are waited on instead of the error group, as abandoned services may never return
+	var errs []error
+	for _, h := range handles {
+		if err := h.Wait(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	r.mtx.Lock()
+	r.waiting = false
+	r.stopped = true
+	r.finished = true
+	r.mtx.Unlock()
+	close(r.done)
+
+	return fail.WrapMany("one or more services failed", errs...)
+}
+
+// Stop gracefully shuts down all services managed by the runner, using the provided context
+// for cancellation and timeout. Services that have been added but not yet started are discarded.
+// Services are shut down in reverse dependency order, see Dependent.
+// Stop returns once all services have exited or their shutdown has timed out, see Handle.Shutdown.
+// Returns an error wrapping all shutdown errors, or ErrRunnerStopped if the runner has already been stopped.
+func (r *Runner) Stop(ctx context.Context) error {
+	handles, ok := r.markStopped()
+	if !ok {
+		return ErrRunnerStopped
+	}
+
+	return r.shutdownHandles(ctx, handles)
+}
+
+// markStopped marks the runner as stopped and discards all pending services.
+// It returns the handles of all started services, or false if the runner has already been stopped.
+func (r *Runner) markStopped() ([]*Handle, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.stopped {
+		return nil, false
+	}
+
+	r.stopped = true
+	r.pending = nil
+
+	return slices.Clone(r.handles), true
+}
+
+// shutdownHandles shuts down the given handles concurrently, in reverse dependency order.
+func (r *Runner) shutdownHandles(ctx context.Context, handles []*Handle) error {
+	wg := sync.WaitGroup{}
+	errs := make([]error, len(handles))
+	for i, h := range handles {
+		wg.Add(1)
+
+		go func(h *Handle) {
+			defer wg.Done()
+
+			// services are shut down in reverse dependency order, i.e. after all services depending on them have exited
+			for _, dependent := range handles {
+				if dependent.dependsOn(h) {
+					select {
+					case <-dependent.exitSig:
+					case <-ctx.Done():
+					}
+				}
+			}
+
+			errs[i] = h.Shutdown(ctx)
+		}(h)
+	}
+
+	wg.Wait()
+
+	errs = slices.DeleteFunc(errs, func(err error) bool {
+		return err == nil
+	})
+
+	return fail.WrapMany("failed to stop runner", errs...)
+}
+
+// Handles returns the handles of all services started by the runner, in the order they were started.
+func (r *Runner) Handles() []*Handle {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return slices.Clone(r.handles)
+}
+
+// checkUsable returns an error if services can no longer be added to or started by the runner.
+// The caller must hold r.mtx.
+func (r *Runner) checkUsable() error {
+	if r.stopped {
+		return ErrRunnerStopped
+	}
+	if r.waiting {
+		return ErrRunnerWaiting
+	}
+
+	return nil
+}
+
+// start starts all pending services and marks the runner as started.
+// If signal handling or the health server is enabled in the runner context, it is set up as well.
+// The caller must hold r.mtx.
+func (r *Runner) start() error {
+	r.signalOnce.Do(func() {
+		if signals, ok := ShutdownSignals(r.ctx); ok {
+			go r.handleSignals(signals)
+		}
+		if signals, ok := ReloadSignals(r.ctx); ok {
+			go r.handleReloadSignals(signals)
+		}
+		if opts, ok := HealthServer(r.ctx); ok {
+			go r.serveHealth(opts)
+		}
+	})
+
+	r.started = true
+	pending := r.pending
+	r.pending = nil
+
+	return r.startAll(pending)
+}
+
+// startAll starts the given services and records their handles.
+// If the dependencies of the services are invalid, none of them is started.
+// The caller must hold r.mtx.
+func (r *Runner) startAll(svcs []Service) error {
+	handles := make([]*Handle, len(svcs))
+	for i, svc := range svcs {
+		handles[i] = prepare(r, svc)
+	}
+
+	var err error
+	if !r.ignoreDependencies {
+		err = resolveDependencies(r.handles, handles)
+	}
+	if err != nil {
+		for _, h := range handles {
+			if !h.hasExited() {
+				_ = shutdownTelemetry(h)
+			}
+		}
+
+		return err
+	}
+
+	for _, h := range handles {
+		run(r, h)
+	}
+	r.handles = append(r.handles, handles...)
+
+	return nil
+}
+
+// escalate is called when a service of the runner did not shut down in time and
+// the ShutdownTimeoutEscalate policy applies. It shuts down all services of the runner.
+func (r *Runner) escalate(err error) {
+	serviceLogger(r.ctx, runnerEnvPrefix(r.ctx)).Error("Service did not shut down in time, shutting down all services", "error", err)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), ShutdownGracePeriod(r.ctx))
+		defer cancel()
+
+		_ = r.Stop(ctx)
+	}()
+}
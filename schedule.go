@@ -0,0 +1,326 @@
+package service
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+//go:generate go tool golang.org/x/tools/cmd/stringer -type OverlapPolicy -trimprefix Overlap
+
+// OverlapPolicy decides what happens when a scheduled run is due while the previous run is still in progress.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the run that is due. This is the default policy.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue runs the run that is due as soon as the previous run has finished.
+	// At most one run is queued; runs that are due while another run is already queued are skipped.
+	OverlapQueue
+)
+
+// ScheduleOption is a function that modifies ScheduleOptions.
+// It is used to configure services created using Periodic and Cron.
+type ScheduleOption = func(*ScheduleOptions)
+
+// ScheduleOptions holds options for scheduled services.
+type ScheduleOptions struct {
+	// Namespace is the namespace of the service.
+	Namespace string
+	// Version is the version of the service.
+	Version string
+	// Jitter is the maximum random delay added to every scheduled run, spreading the load of services
+	// that run on the same schedule. Zero disables jitter.
+	Jitter time.Duration
+	// Overlap decides what happens when a run is due while the previous run is still in progress.
+	// Defaults to OverlapSkip.
+	Overlap OverlapPolicy
+	// RunImmediately determines whether the function is run once as soon as the service is running,
+	// in addition to the schedule.
+	// Defaults to false.
+	RunImmediately bool
+	// Location is the time zone cron schedules are interpreted in.
+	// Defaults to time.Local.
+	Location *time.Location
+}
+
+// ScheduleHealth contains details about the runs of a scheduled service.
+// It is reported as the Details of the Health of services created using Periodic and Cron.
+type ScheduleHealth struct {
+	// Runs is the number of runs that have finished.
+	Runs int `json:"runs"`
+	// LastRun is the time the last finished run was started, or zero if no run has finished yet.
+	LastRun time.Time `json:"lastRun"`
+	// LastError is the error returned by the last finished run, if any.
+	LastError error `json:"lastError,omitempty"`
+	// ConsecutiveFailures is the number of consecutive runs that have failed, up to and including the last one.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	// NextRun is the time the next run is scheduled at, excluding jitter.
+	NextRun time.Time `json:"nextRun"`
+}
+
+// MarshalJSON encodes the ScheduleHealth as a JSON object, where the last error is encoded as its message.
+func (h ScheduleHealth) MarshalJSON() ([]byte, error) {
+	type scheduleHealth ScheduleHealth
+
+	j := struct {
+		scheduleHealth
+		LastError string `json:"lastError,omitempty"`
+	}{scheduleHealth: scheduleHealth(h)}
+	if h.LastError != nil {
+		j.LastError = h.LastError.Error()
+	}
+
+	return json.Marshal(j)
+}
+
+// DefaultScheduleOptions returns a ScheduleOptions struct with default values.
+func DefaultScheduleOptions() *ScheduleOptions {
+	return &ScheduleOptions{
+		Overlap:  OverlapSkip,
+		Location: time.Local,
+	}
+}
+
+// WithScheduleNamespace returns a ScheduleOption that sets the namespace of the service.
+func WithScheduleNamespace(namespace string) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.Namespace = namespace
+	}
+}
+
+// WithScheduleVersion returns a ScheduleOption that sets the version of the service.
+func WithScheduleVersion(version string) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.Version = version
+	}
+}
+
+// WithJitter returns a ScheduleOption that sets the maximum random delay added to every scheduled run.
+func WithJitter(jitter time.Duration) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.Jitter = jitter
+	}
+}
+
+// WithOverlapPolicy returns a ScheduleOption that sets the OverlapPolicy.
+func WithOverlapPolicy(policy OverlapPolicy) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.Overlap = policy
+	}
+}
+
+// WithRunImmediately returns a ScheduleOption that enables or disables running the function
+// as soon as the service is running.
+func WithRunImmediately(enabled bool) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.RunImmediately = enabled
+	}
+}
+
+// WithLocation returns a ScheduleOption that sets the time zone cron schedules are interpreted in.
+// Nil is ignored.
+func WithLocation(location *time.Location) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		if location != nil {
+			o.Location = location
+		}
+	}
+}
+
+// Periodic returns a Service that calls fn every interval while it is running.
+// The interval is measured between the scheduled start times of runs, regardless of how long runs take.
+//
+// A failing run does not stop the service; the error is logged and reported through the Health of the service,
+// which is degraded until a run succeeds again. The context passed to fn is canceled once the service is requested
+// to shut down, and the service waits for a run in progress to return before it stops.
+// A non-positive interval makes the service fail to initialize.
+func Periodic(name string, interval time.Duration, fn func(*Context) error, opts ...ScheduleOption) Service {
+	o := DefaultScheduleOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var err error
+	if interval <= 0 {
+		err = fail.New().
+			Attribute("interval", interval.String()).
+			Msgf("invalid interval %s for periodic service %s", interval, name)
+	}
+
+	return newScheduledService(name, o, fn, func(t time.Time) time.Time {
+		return t.Add(interval)
+	}, err)
+}
+
+// scheduler runs a function according to a schedule and keeps track of its runs.
+type scheduler struct {
+	opts *ScheduleOptions
+	fn   func(*Context) error
+	// next returns the first scheduled time after the given one.
+	next func(time.Time) time.Time
+
+	mtx    sync.RWMutex
+	health ScheduleHealth
+}
+
+// newScheduledService returns a Service running fn according to the given schedule.
+// If err is not nil, the service fails to initialize with it.
+func newScheduledService(name string, opts *ScheduleOptions, fn func(*Context) error, next func(time.Time) time.Time, err error) Service {
+	s := &scheduler{
+		opts: opts,
+		fn:   fn,
+		next: next,
+	}
+
+	return &simpleService{
+		name:      name,
+		namespace: opts.Namespace,
+		version:   opts.Version,
+		fn:        s.run,
+		initFn: func(*Context) error {
+			return err
+		},
+		healthFn: s.healthStatus,
+	}
+}
+
+// run schedules runs of the function until the context is done, and then waits for a run in progress.
+func (s *scheduler) run(ctx *Context) error {
+	// an unbuffered trigger only accepts a run while idle, a buffered one queues a single run
+	var trigger chan struct{}
+	if s.opts.Overlap == OverlapQueue {
+		trigger = make(chan struct{}, 1)
+	} else {
+		trigger = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-trigger:
+				s.runOnce(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	defer wg.Wait()
+
+	fire := func() {
+		select {
+		case trigger <- struct{}{}:
+		default:
+			ctx.Warn("Scheduled run skipped, previous run is still in progress")
+		}
+	}
+
+	if s.opts.RunImmediately {
+		// the worker may not be receiving yet, so wait for it to accept the run
+		select {
+		case trigger <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	next := s.next(time.Now())
+	for {
+		s.setNextRun(next)
+
+		timer := time.NewTimer(time.Until(next) + s.jitter())
+		select {
+		case <-timer.C:
+			fire()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+
+		// skip runs that were missed, e.g. because the process was suspended
+		next = s.next(next)
+		if now := time.Now(); next.Before(now) {
+			next = s.next(now)
+		}
+	}
+}
+
+// runOnce runs the function once and records the outcome.
+func (s *scheduler) runOnce(ctx *Context) {
+	started := time.Now()
+	ctx.Debug("Running scheduled run")
+
+	runFn := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = newPanicError(ctx.identity(), PhaseRunning, r)
+			}
+		}()
+
+		return s.fn(ctx)
+	}
+
+	err := runFn()
+
+	s.mtx.Lock()
+	s.health.Runs++
+	s.health.LastRun = started
+	s.health.LastError = err
+	if err != nil {
+		s.health.ConsecutiveFailures++
+	} else {
+		s.health.ConsecutiveFailures = 0
+	}
+	failures := s.health.ConsecutiveFailures
+	s.mtx.Unlock()
+
+	if err != nil {
+		ctx.Error("Scheduled run failed", "error", err, "consecutiveFailures", failures, "duration", time.Since(started).String())
+	} else {
+		ctx.Debug("Scheduled run finished", "duration", time.Since(started).String())
+	}
+}
+
+// jitter returns a random delay between zero and the configured jitter.
+func (s *scheduler) jitter() time.Duration {
+	if s.opts.Jitter <= 0 {
+		return 0
+	}
+
+	return rand.N(s.opts.Jitter)
+}
+
+// setNextRun records the time of the next scheduled run.
+func (s *scheduler) setNextRun(next time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.health.NextRun = next
+}
+
+// healthStatus reports the health of the running service based on its last run.
+func (s *scheduler) healthStatus() Health {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.health.LastError != nil {
+		return Health{
+			Status:  HealthStatusDegraded,
+			Reason:  "last scheduled run failed",
+			Details: s.health,
+			Error:   s.health.LastError,
+		}
+	}
+
+	return Health{
+		Status:  HealthStatusHealthy,
+		Details: s.health,
+	}
+}
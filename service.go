@@ -1,6 +1,10 @@
 package service
 
-import "sync/atomic"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
 
 // Service represents a long-running component with a well-defined lifecycle.
 // It provides methods for initialization, execution, and graceful shutdown,
@@ -25,6 +29,8 @@ type Service interface {
 	// Run starts the main execution loop of the service.
 	// This method should block until the service is stopped, fails, or the context is cancelled.
 	// If the context is cancelled, the service must begin a graceful shutdown.
+	// When run by a Runner, the context is cancelled right before Shutdown is called,
+	// with a *ShutdownRequestedError as its cause (see context.Cause).
 	// Returns an error if the service fails or is interrupted.
 	Run(*Context) error
 	// Shutdown gracefully stops the service and releases all resources.
@@ -36,6 +42,7 @@ type Service interface {
 
 // Simple returns a Service implementation with the given name, namespace, version, and run function.
 // The returned service is suitable for simple use cases where only a run function is needed.
+// The context passed to fn is canceled once the service is requested to shut down, so fn should return once it is done.
 func Simple(name, namespace, version string, fn func(*Context) error) Service {
 	return &simpleService{
 		name:      name,
@@ -46,14 +53,29 @@ func Simple(name, namespace, version string, fn func(*Context) error) Service {
 }
 
 // simpleService is a basic implementation of the Service interface.
-// It is intended for use with the Simple constructor.
+// It is intended for use with the Simple constructor and the Builder.
 type simpleService struct {
 	name      string
 	namespace string
 	version   string
-	err       error
 	fn        func(*Context) error
 
+	initFn     func(*Context) error
+	shutdownFn func(*Context) error
+	healthFn   func() Health
+
+	// run is the state of the current lifecycle of the service, which is replaced by Initialize.
+	run atomic.Pointer[simpleRun]
+}
+
+// simpleRun holds the state of a single lifecycle of a simpleService, so that a lifecycle that has been
+// abandoned while still running does not prevent the service from being restarted, see Handle.Restart.
+type simpleRun struct {
+	// ctx is the context the lifecycle was initialized with, which is canceled once it is requested to shut down.
+	ctx    context.Context
+	err    error
+	errMtx sync.RWMutex
+
 	started           atomic.Bool
 	stopped           atomic.Bool
 	shutdownRequested atomic.Bool
@@ -75,66 +97,128 @@ func (s *simpleService) Version() string {
 }
 
 // Health returns the current health status of the service.
+// While the service is running, the health function is consulted if one has been set.
 func (s *simpleService) Health() Health {
+	run := s.current()
+	err := run.error()
+
 	status := HealthStatusUnknown
-	if s.stopped.Load() {
-		if s.err != nil {
+	if run.stopped.Load() {
+		if err != nil {
 			status = HealthStatusError
 		} else {
 			status = HealthStatusShutdown
 		}
-	} else if s.started.Load() {
+	} else if run.started.Load() {
+		if s.healthFn != nil {
+			return s.healthFn()
+		}
+
 		status = HealthStatusHealthy
 	}
 
 	return Health{
 		Status: status,
-		Error:  s.err,
+		Error:  err,
 	}
 }
 
 // Error returns the terminal error that caused the service to stop, if any.
 // If the service is still running or has completed successfully, Error returns nil.
 func (s *simpleService) Error() error {
-	return s.err
+	return s.current().error()
 }
 
 // Initialize prepares the service for execution.
-// For simpleService, this is a no-op and always returns nil.
-func (s *simpleService) Initialize(_ *Context) error {
+// For simpleService, this starts a new lifecycle with a fresh state, allowing the service to be restarted,
+// and calls the init function if one has been set. It fails if the previous lifecycle is still running
+// and has not been requested to shut down.
+func (s *simpleService) Initialize(ctx *Context) error {
+	if prev := s.run.Load(); prev != nil && prev.started.Load() && !prev.stopped.Load() && prev.ctx.Err() == nil {
+		return ErrServiceAlreadyRunning
+	}
+
+	run := &simpleRun{ctx: ctx}
+	s.run.Store(run)
+
+	if s.initFn != nil {
+		if err := s.initFn(ctx); err != nil {
+			run.setError(err)
+			run.stopped.Store(true)
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Run starts the main execution loop of the service.
-// It ensures the service is only started once and not after it has been stopped.
+// It ensures the service is only started once per lifecycle and not after it has been stopped.
+// If no run function has been set, Run blocks until the context is done.
 func (s *simpleService) Run(ctx *Context) error {
-	if s.started.Swap(true) {
+	run := s.current()
+	if run.started.Swap(true) {
 		return ErrServiceAlreadyRunning
 	}
 
-	if s.stopped.Load() {
+	if run.stopped.Load() {
 		return ErrServiceAlreadyStopped
 	}
 
 	defer func() {
-		s.stopped.Store(true)
+		run.stopped.Store(true)
 	}()
 
 	// Check for shutdown request during execution
-	if s.shutdownRequested.Load() {
+	if run.shutdownRequested.Load() {
+		return nil
+	}
+
+	if s.fn == nil {
+		<-ctx.Done()
 		return nil
 	}
 
 	err := s.fn(ctx)
 	if err != nil {
-		s.err = err
+		run.setError(err)
 	}
 	return err
 }
 
 // Shutdown gracefully stops the service and releases all resources.
-// For simpleService, this signals the service to stop on next opportunity.
-func (s *simpleService) Shutdown(_ *Context) error {
-	s.shutdownRequested.Store(true)
+// For simpleService, this signals the service to stop on next opportunity and calls the shutdown function
+// if one has been set. A running function is notified through the cancellation of its context, which is done by the runner.
+func (s *simpleService) Shutdown(ctx *Context) error {
+	s.current().shutdownRequested.Store(true)
+
+	if s.shutdownFn != nil {
+		return s.shutdownFn(ctx)
+	}
+
 	return nil
 }
+
+// current returns the state of the current lifecycle, creating it if the service has not been initialized yet.
+func (s *simpleService) current() *simpleRun {
+	if run := s.run.Load(); run != nil {
+		return run
+	}
+
+	s.run.CompareAndSwap(nil, &simpleRun{ctx: context.Background()})
+	return s.run.Load()
+}
+
+func (r *simpleRun) error() error {
+	r.errMtx.RLock()
+	defer r.errMtx.RUnlock()
+
+	return r.err
+}
+
+func (r *simpleRun) setError(err error) {
+	r.errMtx.Lock()
+	defer r.errMtx.Unlock()
+
+	r.err = err
+}
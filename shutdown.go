@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+//go:generate go tool golang.org/x/tools/cmd/stringer -type ShutdownTimeoutPolicy -trimprefix ShutdownTimeout
+
+const (
+	// ShutdownTimeoutEnvVar is the environment variable used to configure the shutdown timeout of a service.
+	// The value must be a duration parsable by time.ParseDuration, e.g. "10s" or "1m".
+	ShutdownTimeoutEnvVar = "SHUTDOWN_TIMEOUT"
+	// ShutdownTimeoutPolicyEnvVar is the environment variable used to configure the process-wide ShutdownTimeoutPolicy.
+	// Valid values are "abandon", "exit" and "escalate" (case-insensitive).
+	ShutdownTimeoutPolicyEnvVar = "SHUTDOWN_TIMEOUT_POLICY"
+	// DefaultShutdownTimeout is the shutdown timeout used if none is configured.
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// ShutdownTimeoutPolicy decides what happens when a service does not shut down within its shutdown timeout.
+type ShutdownTimeoutPolicy int
+
+const (
+	// ShutdownTimeoutAbandon abandons the service: its Handle is marked as failed with a ShutdownTimeoutError,
+	// while the service itself is left running in the background. All other services are unaffected.
+	// If the service is being restarted, only its current lifecycle is abandoned, see Handle.Restart.
+	// This is the default policy.
+	ShutdownTimeoutAbandon ShutdownTimeoutPolicy = iota
+	// ShutdownTimeoutForceExit immediately exits the process with the exit code of the ShutdownTimeoutError.
+	// Deferred functions and all other services are not given a chance to clean up.
+	ShutdownTimeoutForceExit
+	// ShutdownTimeoutEscalate abandons the service like ShutdownTimeoutAbandon, and additionally
+	// shuts down all other services run together with it.
+	ShutdownTimeoutEscalate
+)
+
+// shutdownTimeoutKey is the context key type for storing the shutdown timeout.
+type shutdownTimeoutKey struct{}
+
+// shutdownTimeoutPolicyKey is the context key type for storing the ShutdownTimeoutPolicy.
+type shutdownTimeoutPolicyKey struct{}
+
+// ShutdownTimeoutProvider can optionally be implemented by a Service to define its own shutdown timeout.
+// The returned timeout takes precedence over any timeout configured using the context or environment variables.
+// A non-positive timeout is ignored.
+type ShutdownTimeoutProvider interface {
+	// ShutdownTimeout returns the maximum duration the service may take to shut down.
+	ShutdownTimeout() time.Duration
+}
+
+// WithShutdownTimeout returns a new context with the specified shutdown timeout.
+// The timeout applies to all services run with the returned context, unless they implement ShutdownTimeoutProvider.
+func WithShutdownTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, shutdownTimeoutKey{}, timeout)
+}
+
+// ShutdownTimeout retrieves the shutdown timeout from the context, if present.
+// The boolean reports whether a timeout has been set.
+func ShutdownTimeout(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(shutdownTimeoutKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// ShutdownTimeoutFromEnv reads the shutdown timeout from environment variables.
+// If prefix is provided, it will look for {PREFIX}_SHUTDOWN_TIMEOUT.
+// If prefix is empty, it will look for SERVICE_SHUTDOWN_TIMEOUT.
+// Returns DefaultShutdownTimeout if the variable is unset or not a valid, positive duration.
+func ShutdownTimeoutFromEnv(prefix string) time.Duration {
+	return durationFromEnv(prefix, ShutdownTimeoutEnvVar, DefaultShutdownTimeout)
+}
+
+// WithShutdownTimeoutPolicy returns a new context with the specified ShutdownTimeoutPolicy.
+func WithShutdownTimeoutPolicy(ctx context.Context, policy ShutdownTimeoutPolicy) context.Context {
+	return context.WithValue(ctx, shutdownTimeoutPolicyKey{}, policy)
+}
+
+// ShutdownTimeoutPolicyFromContext retrieves the ShutdownTimeoutPolicy from the context.
+// If no policy is set in the context, it is read using ShutdownTimeoutPolicyFromEnv with the EnvPrefix
+// of the RunOptions of the context, see WithEnvPrefix.
+func ShutdownTimeoutPolicyFromContext(ctx context.Context) ShutdownTimeoutPolicy {
+	if policy, ok := ctx.Value(shutdownTimeoutPolicyKey{}).(ShutdownTimeoutPolicy); ok {
+		return policy
+	}
+
+	return ShutdownTimeoutPolicyFromEnv(runnerEnvPrefix(ctx))
+}
+
+// ShutdownTimeoutPolicyFromEnv reads the process-wide ShutdownTimeoutPolicy from environment variables.
+// If prefix is provided, it will look for {PREFIX}_SHUTDOWN_TIMEOUT_POLICY.
+// If prefix is empty, it will look for SERVICE_SHUTDOWN_TIMEOUT_POLICY.
+// Recognized values (case-insensitive) are:
+//   - "abandon": maps to ShutdownTimeoutAbandon
+//   - "exit", "force-exit", "forceexit": map to ShutdownTimeoutForceExit
+//   - "escalate": maps to ShutdownTimeoutEscalate
+//
+// If the variable is unset or contains an unrecognized value, ShutdownTimeoutAbandon is returned as the default.
+func ShutdownTimeoutPolicyFromEnv(prefix string) ShutdownTimeoutPolicy {
+	switch strings.ToLower(GetEnv(prefix, ShutdownTimeoutPolicyEnvVar)) {
+	case "exit", "force-exit", "forceexit":
+		return ShutdownTimeoutForceExit
+	case "escalate":
+		return ShutdownTimeoutEscalate
+	}
+
+	return ShutdownTimeoutAbandon
+}
+
+// shutdownTimeout determines the shutdown timeout of the given service.
+// The timeout is taken from the first of the following that is set:
+// the service itself (see ShutdownTimeoutProvider), the context, or the environment.
+func shutdownTimeout(ctx context.Context, svc Service) time.Duration {
+	if p, ok := svc.(ShutdownTimeoutProvider); ok && p.ShutdownTimeout() > 0 {
+		return p.ShutdownTimeout()
+	}
+
+	if timeout, ok := ShutdownTimeout(ctx); ok && timeout > 0 {
+		return timeout
+	}
+
+	return ShutdownTimeoutFromEnv(envPrefix(ctx, svc))
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/FlowSeer/fail"
+)
+
+const (
+	// ShutdownGracePeriodEnvVar is the environment variable used to configure the shutdown grace period.
+	// The value must be a duration parsable by time.ParseDuration, e.g. "30s" or "1m".
+	ShutdownGracePeriodEnvVar = "SHUTDOWN_GRACE_PERIOD"
+	// DefaultShutdownGracePeriod is the grace period used if none is configured.
+	DefaultShutdownGracePeriod = 30 * time.Second
+)
+
+// shutdownSignalsKey is the context key type for storing the signals that trigger a graceful shutdown.
+type shutdownSignalsKey struct{}
+
+// shutdownGracePeriodKey is the context key type for storing the shutdown grace period.
+type shutdownGracePeriodKey struct{}
+
+// DefaultShutdownSignals returns the signals that trigger a graceful shutdown if none are specified.
+// These are SIGINT and SIGTERM, the latter being sent by e.g. Kubernetes on pod termination.
+func DefaultShutdownSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM}
+}
+
+// WithShutdownSignals returns a new context that enables signal handling for services run with it.
+// When one of the signals is received, all services are gracefully shut down within the shutdown grace period.
+// When a second signal is received, the process exits immediately.
+// If no signals are provided, DefaultShutdownSignals is used.
+//
+// Signal handling is always enabled for RunAndExit, RunParallelAndExit and RunGroupAndExit,
+// and is opt-in for all other Run functions and the Runner.
+func WithShutdownSignals(ctx context.Context, signals ...os.Signal) context.Context {
+	if len(signals) == 0 {
+		signals = DefaultShutdownSignals()
+	}
+
+	return context.WithValue(ctx, shutdownSignalsKey{}, signals)
+}
+
+// ShutdownSignals retrieves the signals that trigger a graceful shutdown from the context.
+// The boolean reports whether signal handling is enabled for the context.
+func ShutdownSignals(ctx context.Context) ([]os.Signal, bool) {
+	signals, ok := ctx.Value(shutdownSignalsKey{}).([]os.Signal)
+	return signals, ok
+}
+
+// WithShutdownGracePeriod returns a new context with the specified shutdown grace period.
+// The grace period is the time services are given to shut down after a shutdown signal has been received.
+func WithShutdownGracePeriod(ctx context.Context, gracePeriod time.Duration) context.Context {
+	return context.WithValue(ctx, shutdownGracePeriodKey{}, gracePeriod)
+}
+
+// ShutdownGracePeriod retrieves the shutdown grace period from the context.
+// If no grace period is set in the context, it is read using ShutdownGracePeriodFromEnv with the EnvPrefix
+// of the RunOptions of the context, see WithEnvPrefix.
+func ShutdownGracePeriod(ctx context.Context) time.Duration {
+	if gracePeriod, ok := ctx.Value(shutdownGracePeriodKey{}).(time.Duration); ok {
+		return gracePeriod
+	}
+
+	return ShutdownGracePeriodFromEnv(runnerEnvPrefix(ctx))
+}
+
+// ShutdownGracePeriodFromEnv reads the shutdown grace period from environment variables.
+// If prefix is provided, it will look for {PREFIX}_SHUTDOWN_GRACE_PERIOD.
+// If prefix is empty, it will look for SERVICE_SHUTDOWN_GRACE_PERIOD.
+// Returns DefaultShutdownGracePeriod if the variable is unset or not a valid, positive duration.
+func ShutdownGracePeriodFromEnv(prefix string) time.Duration {
+	return durationFromEnv(prefix, ShutdownGracePeriodEnvVar, DefaultShutdownGracePeriod)
+}
+
+// handleSignals gracefully stops the runner when one of the given signals is received,
+// and forcefully exits the process when a second signal is received.
+// It returns once the runner has finished waiting for its services.
+func (r *Runner) handleSignals(signals []os.Signal) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+
+	var sig os.Signal
+	select {
+	case sig = <-sigCh:
+	case <-r.done:
+		return
+	}
+
+	gracePeriod := ShutdownGracePeriod(r.ctx)
+	serviceLogger(r.ctx, runnerEnvPrefix(r.ctx)).Info("Received signal, shutting down",
+		"signal", sig.String(),
+		"gracePeriod", gracePeriod.String())
+
+	// the grace period must not be affected by cancellation of the runner context
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.ctx), gracePeriod)
+	defer cancel()
+
+	go func() {
+		_ = r.Stop(ctx)
+	}()
+
+	select {
+	case sig = <-sigCh:
+	case <-r.done:
+		return
+	}
+
+	err := fail.New().
+		Attribute("signal", sig.String()).
+		ExitCode(signalExitCode(sig)).
+		Msg("received second signal, forcing exit")
+
+	fail.PrintPretty(err)
+	os.Exit(fail.ExitCode(err))
+}
+
+// signalExitCode returns the conventional exit code for a process terminated by the given signal.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return fail.DefaultExitCode
+}
+
+// withDefaultShutdownSignals enables signal handling with the default signals,
+// unless signal handling has already been configured in the context.
+func withDefaultShutdownSignals(ctx context.Context) context.Context {
+	if _, ok := ShutdownSignals(ctx); ok {
+		return ctx
+	}
+
+	return WithShutdownSignals(ctx)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+//go:generate go tool golang.org/x/tools/cmd/stringer -type RestartPolicy -trimprefix Restart
+//go:generate go tool golang.org/x/tools/cmd/stringer -type SupervisorStrategy -trimprefix Strategy
+
+// RestartPolicy decides whether a service is restarted after its Run method has returned.
+type RestartPolicy int
+
+const (
+	// RestartNever never restarts the service. This is the default policy.
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts the service if it returned an error.
+	RestartOnFailure
+	// RestartAlways restarts the service whenever it returns, regardless of whether it returned an error.
+	RestartAlways
+)
+
+// SupervisorStrategy decides which services are restarted together when a supervised service is restarted.
+type SupervisorStrategy int
+
+const (
+	// StrategyOneForOne only restarts the service that returned. This is the default strategy.
+	StrategyOneForOne SupervisorStrategy = iota
+	// StrategyOneForAll restarts all services run together with the service that returned.
+	StrategyOneForAll
+	// StrategyRestForOne restarts the service that returned and all services that were started after it.
+	StrategyRestForOne
+)
+
+// supervisionKey is the context key type for storing the Supervision.
+type supervisionKey struct{}
+
+// Backoff configures the delay between consecutive restarts of a service.
+// The delay starts at Initial and is multiplied by Multiplier for every consecutive restart, up to Max.
+type Backoff struct {
+	// Initial is the delay before the first restart.
+	Initial time.Duration
+	// Max is the maximum delay between restarts. Zero means unbounded.
+	Max time.Duration
+	// Multiplier is the factor the delay is multiplied with for every consecutive restart.
+	// Values smaller than 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction by which the delay is randomly varied in both directions, e.g. 0.2 for ±20%.
+	Jitter float64
+}
+
+// Supervision configures how services are restarted by their supervisor.
+type Supervision struct {
+	// Policy decides whether a service is restarted after it has returned.
+	// Services can override the policy by implementing RestartPolicyProvider.
+	Policy RestartPolicy
+	// Strategy decides which services are restarted together.
+	Strategy SupervisorStrategy
+	// Backoff configures the delay between consecutive restarts.
+	Backoff Backoff
+	// MaxRestarts is the maximum number of restarts of a single service within Window.
+	// If the budget is exhausted, the service is not restarted anymore and fails with a *CrashLoopError.
+	// Zero or less means unlimited restarts.
+	MaxRestarts int
+	// Window is the sliding time window MaxRestarts applies to.
+	// A service that ran for at least Window before returning also starts over with the initial backoff delay.
+	Window time.Duration
+}
+
+// RestartPolicyProvider can optionally be implemented by a Service to define its own restart policy.
+// The returned policy takes precedence over the policy of the Supervision.
+type RestartPolicyProvider interface {
+	// RestartPolicy returns the restart policy of the service.
+	RestartPolicy() RestartPolicy
+}
+
+// DefaultBackoff returns the Backoff used by DefaultSupervision.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    500 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// DefaultSupervision returns the Supervision used if none is configured.
+// Services are never restarted, unless they implement RestartPolicyProvider.
+func DefaultSupervision() Supervision {
+	return Supervision{
+		Policy:      RestartNever,
+		Strategy:    StrategyOneForOne,
+		Backoff:     DefaultBackoff(),
+		MaxRestarts: 5,
+		Window:      time.Minute,
+	}
+}
+
+// WithSupervision returns a new context with the specified Supervision.
+// All services run with the returned context are supervised accordingly.
+func WithSupervision(ctx context.Context, supervision Supervision) context.Context {
+	return context.WithValue(ctx, supervisionKey{}, supervision)
+}
+
+// SupervisionFromContext retrieves the Supervision from the context.
+// If no Supervision is set in the context, DefaultSupervision is returned.
+func SupervisionFromContext(ctx context.Context) Supervision {
+	if supervision, ok := ctx.Value(supervisionKey{}).(Supervision); ok {
+		return supervision
+	}
+
+	return DefaultSupervision()
+}
+
+// Supervise runs multiple services as a supervised group using the provided context and returns
+// a slice of Handles, one for each service. Services are restarted according to the given Supervision.
+// If a service exhausts its restart budget, the context is canceled for all services in the group.
+func Supervise(ctx context.Context, supervision Supervision, svcs ...Service) []*Handle {
+	return RunGroup(WithSupervision(ctx, supervision), svcs...)
+}
+
+// Delay returns the delay before the restart following the given number of consecutive restarts.
+func (b Backoff) Delay(consecutive int) time.Duration {
+	delay := float64(b.Initial) * math.Pow(max(b.Multiplier, 1), float64(consecutive))
+	if b.Max > 0 {
+		delay = min(delay, float64(b.Max))
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(max(delay, 0))
+}
+
+// supervisor runs the lifecycles of the services of a runner and restarts them according to its Supervision.
+type supervisor struct {
+	// runner is the runner whose services are supervised.
+	runner *Runner
+	// supervision configures how services are restarted.
+	supervision Supervision
+}
+
+// supervise runs lifecycles of the service managed by the handle until the service is not restarted anymore.
+// It returns the error of the last lifecycle.
+func (s *supervisor) supervise(h *Handle) error {
+	var (
+		err         error
+		restarts    []time.Time
+		consecutive int
+	)
+
+	for {
+		lc, ok := h.beginLifecycle()
+		if !ok {
+			return err
+		}
+
+		started := time.Now()
+		err = runBlocking(lc.ctx, h.svc, h, lc)
+
+		// a stopped service, or one whose runner is done, is never restarted
+		if h.isStopping() || h.hasExited() || h.svcContext.Err() != nil {
+			return err
+		}
+
+		delay := time.Duration(0)
+		if !lc.restart.Load() {
+			if !s.shouldRestart(h.svc, err) {
+				return err
+			}
+
+			now := time.Now()
+			restarts = slices.DeleteFunc(restarts, func(t time.Time) bool {
+				return now.Sub(t) >= s.supervision.Window
+			})
+			if s.supervision.MaxRestarts > 0 && len(restarts) >= s.supervision.MaxRestarts {
+				h.svcContext.Error("Restart budget exhausted", "restarts", len(restarts), "error", err)
+
+				return &CrashLoopError{
+					Service:  h.String(),
+					Restarts: len(restarts),
+					Window:   s.supervision.Window,
+					Err:      err,
+				}
+			}
+			restarts = append(restarts, now)
+
+			if now.Sub(started) >= s.supervision.Window {
+				consecutive = 0
+			}
+			delay = s.supervision.Backoff.Delay(consecutive)
+			consecutive++
+
+			s.restartSiblings(h)
+		}
+
+		h.setPhase(PhaseRestarting, err)
+		h.svcContext.Warn("Restarting", "delay", delay.String(), "restarts", h.Restarts(), "error", err)
+
+		select {
+		case <-time.After(delay):
+		case <-h.stopSig:
+			return err
+		case <-h.svcContext.Done():
+			return err
+		}
+
+		h.restarts.Add(1)
+	}
+}
+
+// shouldRestart reports whether the given service should be restarted after returning the given error.
+func (s *supervisor) shouldRestart(svc Service, err error) bool {
+	policy := s.supervision.Policy
+	if p, ok := svc.(RestartPolicyProvider); ok {
+		policy = p.RestartPolicy()
+	}
+
+	switch policy {
+	case RestartOnFailure:
+		return err != nil
+	case RestartAlways:
+		return true
+	default:
+		return false
+	}
+}
+
+// restartSiblings restarts the services run together with the service managed by the given handle,
+// as required by the supervisor strategy.
+func (s *supervisor) restartSiblings(h *Handle) {
+	if s.supervision.Strategy == StrategyOneForOne {
+		return
+	}
+
+	handles := s.runner.Handles()
+	i := slices.Index(handles, h)
+	if i < 0 {
+		return
+	}
+
+	switch s.supervision.Strategy {
+	case StrategyOneForAll:
+		handles = slices.Delete(handles, i, i+1)
+	case StrategyRestForOne:
+		handles = handles[i+1:]
+	}
+
+	for _, sibling := range handles {
+		sibling.requestRestart()
+	}
+}
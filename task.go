@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/FlowSeer/fail"
+)
+
+// taskGroup tracks the background tasks started using Context.Go during a single lifecycle of a service.
+type taskGroup struct {
+	// cancel cancels the context of the lifecycle the tasks belong to.
+	cancel context.CancelCauseFunc
+
+	mtx sync.Mutex
+	// running is the number of tasks that have not returned yet.
+	running int
+	// closed is set once the group is waited for, after which no new tasks are accepted.
+	closed bool
+	// done is closed once the group is closed and all tasks have returned.
+	done chan struct{}
+	errs []error
+}
+
+// newTaskGroup returns a taskGroup whose first failing task cancels the lifecycle using the given function.
+func newTaskGroup(cancel context.CancelCauseFunc) *taskGroup {
+	return &taskGroup{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+}
+
+// Go runs fn in a new goroutine as a background task of the service, identified by the given name.
+//
+// Background tasks are bound to the current run of the service: the Context passed to fn is canceled
+// once the service is requested to shut down, and the service is not considered stopped before all of its
+// tasks have returned. Waiting for tasks is bounded by the shutdown timeout of the service.
+//
+// If a task returns an error or panics, the error is logged and the Context of the service is canceled
+// with it as the cause, so that Run can return. The errors of all failed tasks are reported as errors of the service.
+// Errors returned by tasks after the Context has been canceled are ignored if they are context errors.
+//
+// Tasks cannot be started anymore once the service has shut down; such calls are logged and ignored.
+func (c *Context) Go(name string, fn func(*Context) error) {
+	taskCtx := c.withContext(c.Context)
+	taskCtx.logger = c.logger.With("task", name)
+
+	if c.tasks == nil || !c.tasks.add() {
+		taskCtx.Warn("Background task not started, service has already shut down")
+		return
+	}
+
+	go func() {
+		err := runTask(taskCtx, name, fn)
+		c.tasks.finish(taskCtx, err)
+	}()
+}
+
+// runTask runs fn, recovering from panics as a PanicError.
+func runTask(ctx *Context, name string, fn func(*Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = newPanicError(ctx.identity(), PhaseRunning, r)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// add registers a new task. It reports false if the group is closed.
+func (g *taskGroup) add() bool {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.closed {
+		return false
+	}
+
+	g.running++
+
+	return true
+}
+
+// finish records that a task has returned with the given error.
+func (g *taskGroup) finish(ctx *Context, err error) {
+	if err != nil && ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		err = nil
+	}
+
+	if err != nil {
+		ctx.Error("Background task failed", "error", err)
+		g.cancel(err)
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if err != nil {
+		g.errs = append(g.errs, err)
+	}
+
+	g.running--
+	if g.closed && g.running == 0 {
+		close(g.done)
+	}
+}
+
+// wait closes the group and returns a channel that is closed once all tasks have returned.
+func (g *taskGroup) wait() <-chan struct{} {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if !g.closed {
+		g.closed = true
+		if g.running == 0 {
+			close(g.done)
+		}
+	}
+
+	return g.done
+}
+
+// err returns the errors of all failed tasks, or nil if no task has failed.
+func (g *taskGroup) err() error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if len(g.errs) == 0 {
+		return nil
+	}
+
+	return fail.WrapMany("one or more background tasks failed", g.errs...)
+}